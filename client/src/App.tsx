@@ -1,5 +1,5 @@
 import { useEffect, useState } from "react";
-import { fetchMe, logout } from "./api";
+import { exchangeOAuthCode, fetchMe, logout } from "./api";
 // import reactLogo from "./assets/react.svg";
 // import viteLogo from "/vite.svg";
 import GoogleLoginButton from "./components/GoogleLoginButton";
@@ -23,20 +23,19 @@ function App() {
   const [selectedArticleId, setSelectedArticleId] = useState<number | null>(null);
 
   useEffect(() => {
-    // 检查URL参数中是否有token
+    // 检查URL参数中是否有 OAuth 回调返回的授权码
     const urlParams = new URLSearchParams(window.location.search);
-    const token = urlParams.get("token");
+    const code = urlParams.get("code");
+    const state = urlParams.get("state");
 
-    if (token) {
-      // 存储token到localStorage
-      localStorage.setItem("access_token", token);
-
-      // 清除URL中的token参数
+    // 清除URL中的授权码参数
+    if (code || urlParams.get("error")) {
       window.history.replaceState({}, document.title, window.location.pathname);
     }
 
-    // 获取用户信息
-    fetchMe()
+    // 用授权码换取 token 后获取用户信息
+    (code ? exchangeOAuthCode(code, state).catch(() => undefined) : Promise.resolve())
+      .then(() => fetchMe())
       .then(setUser)
       .catch(() => setUser(null))
       .finally(() => setLoading(false));
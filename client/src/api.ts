@@ -1,17 +1,24 @@
+// Cookie 会话模式：token 保存在 HttpOnly Cookie 中，写操作需回传 csrf_token Cookie 的值
+function csrfHeaders(): Record<string, string> {
+  const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]+)/);
+  return match ? { "X-CSRF-Token": decodeURIComponent(match[1]) } : {};
+}
+
 export async function fetchMe() {
-  const token = localStorage.getItem("access_token");
-  const headers: Record<string, string> = {
-    "credentials": "include"
-  };
-  
-  if (token) {
-    headers["Authorization"] = `Bearer ${token}`;
-  }
-  
-  const res = await fetch("/api/users/me", {
+  let res = await fetch("/api/users/me", {
     credentials: "include", // 关键！带上 cookie
-    headers,
   });
+  if (res.status === 401) {
+    // access token 过期时用 refresh_token Cookie 刷新一次
+    const refreshed = await fetch("/api/auth/refresh", {
+      method: "POST",
+      credentials: "include",
+      headers: csrfHeaders(),
+    });
+    if (refreshed.ok) {
+      res = await fetch("/api/users/me", { credentials: "include" });
+    }
+  }
   if (!res.ok) throw new Error("Not logged in");
   return res.json();
 }
@@ -20,24 +27,16 @@ export async function logout() {
   await fetch("/api/auth/logout", {
     method: "POST",
     credentials: "include",
+    headers: csrfHeaders(),
   });
 }
 
 // 文章相关API函数
 export async function createArticle(formData: FormData) {
-  const token = localStorage.getItem("access_token");
-  const headers: Record<string, string> = {
-    "credentials": "include"
-  };
-  
-  if (token) {
-    headers["Authorization"] = `Bearer ${token}`;
-  }
-
   const response = await fetch("/api/articles/with-image", {
     method: "POST",
     credentials: "include",
-    headers,
+    headers: csrfHeaders(),
     body: formData
   });
 
@@ -87,6 +86,21 @@ export async function getArticle(articleId: number) {
   return response.json();
 }
 
+// 点赞或取消点赞，重复调用不会重复计数
+export async function likeArticle(articleId: number, liked: boolean) {
+  const response = await fetch(`/api/articles/${articleId}/like`, {
+    method: liked ? "PUT" : "DELETE",
+    credentials: "include",
+    headers: csrfHeaders(),
+  });
+
+  if (!response.ok) {
+    throw new Error(`HTTP error! status: ${response.status}`);
+  }
+
+  return response.json();
+}
+
 export const getUserInfo = async () => {
   try {
     const response = await fetch('/api/user/me', {
@@ -108,3 +122,56 @@ export const getUserInfo = async () => {
     throw error;
   }
 };
+
+// OAuth 授权码 + PKCE 相关函数
+const PKCE_VERIFIER_KEY = "oauth_code_verifier";
+const OAUTH_STATE_KEY = "oauth_state";
+
+function base64UrlEncode(bytes: Uint8Array) {
+  let str = "";
+  bytes.forEach((b) => (str += String.fromCharCode(b)));
+  return btoa(str).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+function randomString(size = 32) {
+  const bytes = new Uint8Array(size);
+  crypto.getRandomValues(bytes);
+  return base64UrlEncode(bytes);
+}
+
+// 生成 code_verifier 和 state 并保存到 sessionStorage，返回登录跳转需要的参数
+export async function createPkceParams() {
+  const verifier = randomString();
+  const state = randomString(16);
+  const digest = await crypto.subtle.digest("SHA-256", new TextEncoder().encode(verifier));
+  sessionStorage.setItem(PKCE_VERIFIER_KEY, verifier);
+  sessionStorage.setItem(OAUTH_STATE_KEY, state);
+  return {
+    code_challenge: base64UrlEncode(new Uint8Array(digest)),
+    code_challenge_method: "S256",
+    state,
+  };
+}
+
+// 用回调返回的授权码换取 token
+export async function exchangeOAuthCode(code: string, state: string | null) {
+  const verifier = sessionStorage.getItem(PKCE_VERIFIER_KEY);
+  const expectedState = sessionStorage.getItem(OAUTH_STATE_KEY);
+  sessionStorage.removeItem(PKCE_VERIFIER_KEY);
+  sessionStorage.removeItem(OAUTH_STATE_KEY);
+  if (!verifier || state !== expectedState) {
+    throw new Error("Invalid OAuth state");
+  }
+
+  const res = await fetch("/api/auth/token", {
+    method: "POST",
+    credentials: "include",
+    headers: { "Content-Type": "application/json", "X-Session-Mode": "cookie" },
+    body: JSON.stringify({ code, code_verifier: verifier }),
+  });
+  if (!res.ok) throw new Error("Token exchange failed");
+  const body = await res.json();
+  // 开启两步验证的账号需要先提交验证码，Web 端暂未提供该界面
+  if (body.data.mfa_required) throw new Error("MFA required");
+  return body.data;
+}
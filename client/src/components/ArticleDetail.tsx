@@ -1,5 +1,5 @@
 import React, { useState, useEffect } from 'react';
-import { getArticle } from '../api';
+import { getArticle, likeArticle } from '../api';
 import './ArticleDetail.css';
 
 interface Article {
@@ -9,6 +9,7 @@ interface Article {
   category: string;
   like_count: number;
   comment_count: number;
+  liked_by_me?: boolean;
   image_url?: string;
   created_at: string;
   updated_at?: string;
@@ -46,6 +47,7 @@ const ArticleDetail: React.FC<ArticleDetailProps> = ({ articleId, onBack }) => {
       if (response.success) {
         setArticle(response.data);
         setLocalLikeCount(response.data.like_count);
+        setLiked(!!response.data.liked_by_me);
       } else {
         throw new Error(response.error_message || '获取文章详情失败');
       }
@@ -56,11 +58,16 @@ const ArticleDetail: React.FC<ArticleDetailProps> = ({ articleId, onBack }) => {
     }
   };
 
-  const handleLike = () => {
-    if (!liked) {
-      setLiked(true);
-      setLocalLikeCount(prev => prev + 1);
-      // TODO: 调用后端点赞API
+  const handleLike = async () => {
+    try {
+      const response = await likeArticle(articleId, !liked);
+      if (response.success) {
+        setLiked(response.data.liked);
+        setLocalLikeCount(response.data.like_count);
+      }
+    } catch (err) {
+      // 未登录时点赞接口返回 401
+      console.error('点赞失败:', err);
     }
   };
 
@@ -144,7 +151,6 @@ const ArticleDetail: React.FC<ArticleDetailProps> = ({ articleId, onBack }) => {
             <button 
               onClick={handleLike}
               className={`like-button ${liked ? 'liked' : ''}`}
-              disabled={liked}
             >
               ❤️ {localLikeCount}
             </button>
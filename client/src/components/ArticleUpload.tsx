@@ -108,8 +108,6 @@ const ArticleUpload: React.FC = () => {
       submitFormData.append('title', formData.title);
       submitFormData.append('body_text', formData.body_text);
       submitFormData.append('category', formData.category);
-      submitFormData.append('like_count', '0');
-      submitFormData.append('comment_count', '0');
       
       if (formData.image) {
         submitFormData.append('image', formData.image);
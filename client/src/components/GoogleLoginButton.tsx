@@ -1,20 +1,24 @@
 import React, { useState } from "react";
+import { createPkceParams } from "../api";
 
 const GoogleLoginButton: React.FC = () => {
   const [isLoading, setIsLoading] = useState(false);
 
-  const handleLogin = () => {
+  const handleLogin = async () => {
     try {
       setIsLoading(true);
-      // 获取当前前端地址作为重定向参数
-      const currentURL = window.location.origin;
-      const redirectParam = encodeURIComponent(currentURL);
+      // 获取当前前端地址作为重定向参数，并附带 PKCE 参数
+      const params = new URLSearchParams({
+        client_id: 'web',
+        redirect: window.location.origin,
+        ...(await createPkceParams()),
+      });
       
       // 统一使用www.ifoodme.com，后端已通过nginx转发
       const apiBaseUrl = 'https://www.ifoodme.com';
       
       // 跳转到Google登录接口
-      window.location.href = `${apiBaseUrl}/api/auth/google?redirect=${redirectParam}`;
+      window.location.href = `${apiBaseUrl}/api/auth/google?${params.toString()}`;
     } catch (error) {
       console.error('Google login error:', error);
       alert('登录过程中发生错误，请稍后重试');
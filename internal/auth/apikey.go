@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"ar-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyTag API Key 的固定前缀，便于在日志和代码扫描中识别泄露的密钥
+const apiKeyTag = "ark_"
+
+// apiKeyTouchInterval 最近使用时间的最小更新间隔，避免每个请求都写库
+const apiKeyTouchInterval = time.Minute
+
+var (
+	ErrAPIKeyInvalid = errors.New("api key invalid")
+	ErrAPIKeyExpired = errors.New("api key expired")
+)
+
+// apiKeyForbiddenScopes 不允许授予 API Key 的权限：管理账号、凭证和系统的操作必须由人登录完成。
+// 文章需要记录作者，API Key 不代表任何用户，因此也不能发布文章
+var apiKeyForbiddenScopes = []Permission{
+	PermArticlesWrite,
+	PermUsersWrite,
+	PermTokensManage,
+	PermAPIKeysManage,
+	PermSystemManage,
+}
+
+// GenerateAPIKey 生成新的 API Key，返回明文密钥和用于辨认的前缀
+func GenerateAPIKey() (key string, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = apiKeyTag + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:len(apiKeyTag)+8], nil
+}
+
+// HashAPIKey 计算 API Key 的 SHA-256 哈希，数据库只保存哈希值
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsValidAPIKeyScope 判断权限能否授予 API Key
+func IsValidAPIKeyScope(perm Permission) bool {
+	for _, p := range apiKeyForbiddenScopes {
+		if p == perm {
+			return false
+		}
+	}
+	return IsValidPermission(perm)
+}
+
+// APIKeyScopes 解析 API Key 保存的权限列表，丢弃创建后才被禁止授予 API Key 的权限
+func APIKeyScopes(s string) []Permission {
+	var scopes []Permission
+	for _, p := range ParseScopes(s) {
+		if IsValidAPIKeyScope(p) {
+			scopes = append(scopes, p)
+		}
+	}
+	return scopes
+}
+
+// ParseScopes 解析逗号分隔的权限列表
+func ParseScopes(s string) []Permission {
+	var scopes []Permission
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			scopes = append(scopes, Permission(p))
+		}
+	}
+	return scopes
+}
+
+// AuthenticateAPIKey 校验请求携带的 API Key，成功时按间隔更新最近使用时间和 IP
+func AuthenticateAPIKey(db *gorm.DB, key string, ip string, now time.Time) (*model.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyTag) {
+		return nil, ErrAPIKeyInvalid
+	}
+	var apiKey model.APIKey
+	err := db.Where("key_hash = ? AND revoked_at IS NULL", HashAPIKey(key)).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval || apiKey.LastUsedIP != ip {
+		db.Model(&model.APIKey{}).Where("key_id = ?", apiKey.KeyID).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		})
+		apiKey.LastUsedAt = &now
+		apiKey.LastUsedIP = ip
+	}
+	return &apiKey, nil
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes string
+		want   []Permission
+	}{
+		{"普通权限", "stores:read, menus:write", []Permission{PermStoresRead, PermMenusWrite}},
+		{"丢弃禁止授予的权限", "articles:write,stores:read,users:write", []Permission{PermStoresRead}},
+		{"丢弃未知权限", "stores:read,unknown:scope", []Permission{PermStoresRead}},
+		{"空", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := APIKeyScopes(tt.scopes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("APIKeyScopes(%q) = %v, want %v", tt.scopes, got, tt.want)
+			}
+		})
+	}
+	if IsValidAPIKeyScope(PermArticlesWrite) {
+		t.Error("articles:write must not be grantable to API keys")
+	}
+}
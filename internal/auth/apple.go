@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"ar-backend/pkg/jwks"
+)
+
+const (
+	defaultAppleJWKSURL = "https://appleid.apple.com/auth/keys"
+	defaultAppleIssuer  = "https://appleid.apple.com"
+
+	// ApplePrivateRelayDomain Apple「隐藏邮箱」中转地址的域名
+	ApplePrivateRelayDomain = "privaterelay.appleid.com"
+)
+
+// AppleIdentity 从 Apple identity token 中解析出的用户信息
+type AppleIdentity struct {
+	Subject        string
+	Email          string
+	EmailVerified  bool
+	IsPrivateEmail bool
+}
+
+var (
+	appleVerifierOnce sync.Once
+	appleVerifier     *IDTokenVerifier
+)
+
+// getAppleVerifier 根据环境变量初始化 Apple token 校验器
+// APPLE_CLIENT_IDS 为逗号分隔的 Bundle ID / Service ID，APPLE_JWKS_URL 可指向本地测试服务
+func getAppleVerifier() *IDTokenVerifier {
+	appleVerifierOnce.Do(func() {
+		jwksURL := os.Getenv("APPLE_JWKS_URL")
+		if jwksURL == "" {
+			jwksURL = defaultAppleJWKSURL
+		}
+		issuer := os.Getenv("APPLE_ISSUER")
+		if issuer == "" {
+			issuer = defaultAppleIssuer
+		}
+		appleVerifier = &IDTokenVerifier{
+			Issuers:   []string{issuer},
+			Audiences: splitEnvList("APPLE_CLIENT_IDS"),
+			Keys:      jwks.NewRemoteKeySet(jwksURL, 24*time.Hour),
+		}
+	})
+	return appleVerifier
+}
+
+// VerifyAppleIDToken 校验 Sign in with Apple 的 identity token，nonce 为空时不校验
+func VerifyAppleIDToken(idToken string, nonce string) (*AppleIdentity, error) {
+	claims, err := getAppleVerifier().Verify(idToken)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyNonce(claims, nonce) {
+		return nil, fmt.Errorf("%w: nonce 不匹配", errIDTokenInvalid)
+	}
+
+	sub, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	email = strings.ToLower(strings.TrimSpace(email))
+	return &AppleIdentity{
+		Subject:        sub,
+		Email:          email,
+		EmailVerified:  claimBool(claims, "email_verified"),
+		IsPrivateEmail: claimBool(claims, "is_private_email") || IsApplePrivateRelay(email),
+	}, nil
+}
+
+// IsApplePrivateRelay 判断邮箱是否为 Apple 中转邮箱
+func IsApplePrivateRelay(email string) bool {
+	return strings.HasSuffix(strings.ToLower(email), "@"+ApplePrivateRelayDomain)
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// useAppleVerifier 让 VerifyAppleIDToken 在测试期间使用指定的校验器
+func useAppleVerifier(t *testing.T, v *IDTokenVerifier) {
+	t.Helper()
+	appleVerifierOnce = sync.Once{}
+	appleVerifierOnce.Do(func() { appleVerifier = v })
+	t.Cleanup(func() {
+		appleVerifierOnce = sync.Once{}
+		appleVerifier = nil
+	})
+}
+
+func TestVerifyAppleIDToken(t *testing.T) {
+	idp := newTestIdP(t)
+	useAppleVerifier(t, idp.verifier(defaultAppleIssuer, "com.example.app"))
+
+	sum := sha256.Sum256([]byte("raw-nonce"))
+	hashedNonce := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		nonce   string
+		want    AppleIdentity
+		wantErr bool
+	}{
+		{
+			name:   "普通邮箱",
+			claims: jwt.MapClaims{"email": "User@Example.com", "email_verified": true},
+			want:   AppleIdentity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true},
+		},
+		{
+			name:   "email_verified 为字符串",
+			claims: jwt.MapClaims{"email": "user@example.com", "email_verified": "true", "is_private_email": "false"},
+			want:   AppleIdentity{Subject: "subject-1", Email: "user@example.com", EmailVerified: true},
+		},
+		{
+			name:   "email_verified 为字符串 false",
+			claims: jwt.MapClaims{"email": "user@example.com", "email_verified": "false"},
+			want:   AppleIdentity{Subject: "subject-1", Email: "user@example.com"},
+		},
+		{
+			name:   "中转邮箱（is_private_email 字符串）",
+			claims: jwt.MapClaims{"email": "abc123@privaterelay.appleid.com", "email_verified": "true", "is_private_email": "true"},
+			want:   AppleIdentity{Subject: "subject-1", Email: "abc123@privaterelay.appleid.com", EmailVerified: true, IsPrivateEmail: true},
+		},
+		{
+			name:   "中转邮箱（缺少 is_private_email 时按域名判断）",
+			claims: jwt.MapClaims{"email": "ABC123@PrivateRelay.AppleID.com", "email_verified": true},
+			want:   AppleIdentity{Subject: "subject-1", Email: "abc123@privaterelay.appleid.com", EmailVerified: true, IsPrivateEmail: true},
+		},
+		{
+			name:   "后续登录不返回邮箱",
+			claims: jwt.MapClaims{},
+			want:   AppleIdentity{Subject: "subject-1"},
+		},
+		{
+			name:   "token 中为 SHA-256 nonce",
+			claims: jwt.MapClaims{"nonce": hashedNonce},
+			nonce:  "raw-nonce",
+			want:   AppleIdentity{Subject: "subject-1"},
+		},
+		{
+			name:   "token 中为原始 nonce",
+			claims: jwt.MapClaims{"nonce": "raw-nonce"},
+			nonce:  "raw-nonce",
+			want:   AppleIdentity{Subject: "subject-1"},
+		},
+		{
+			name:    "nonce 不匹配",
+			claims:  jwt.MapClaims{"nonce": hashedNonce},
+			nonce:   "other-nonce",
+			wantErr: true,
+		},
+		{
+			name:    "token 中缺少 nonce",
+			claims:  jwt.MapClaims{},
+			nonce:   "raw-nonce",
+			wantErr: true,
+		},
+		{
+			name:    "aud 为其他 App",
+			claims:  jwt.MapClaims{"aud": "com.example.other"},
+			wantErr: true,
+		},
+		{
+			name:    "iss 不是 Apple",
+			claims:  jwt.MapClaims{"iss": testIssuer},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{"iss": defaultAppleIssuer, "aud": "com.example.app"}
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+			identity, err := VerifyAppleIDToken(idp.sign(t, validClaims(claims)), tt.nonce)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyAppleIDToken err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, errIDTokenInvalid) {
+					t.Errorf("err = %v, want it to wrap errIDTokenInvalid", err)
+				}
+				return
+			}
+			if *identity != tt.want {
+				t.Errorf("identity = %+v, want %+v", *identity, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsApplePrivateRelay(t *testing.T) {
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"abc@privaterelay.appleid.com", true},
+		{"ABC@PRIVATERELAY.APPLEID.COM", true},
+		{"abc@example.com", false},
+		{"abc@evilprivaterelay.appleid.com", false},
+		{"privaterelay.appleid.com@example.com", false},
+	}
+	for _, tt := range tests {
+		if got := IsApplePrivateRelay(tt.email); got != tt.want {
+			t.Errorf("IsApplePrivateRelay(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+}
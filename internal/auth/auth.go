@@ -95,4 +95,25 @@ func NewAuth() {
 	googleProvider := google.New(googleClientId, googleClientSecret, callbackURL)
 	googleProvider.SetPrompt("select_account") // 强制显示 Google 账号选择界面
 	goth.UseProviders(googleProvider)
+
+	// 设置 LINE Login 配置，未配置 Channel 时不启用网页跳转登录
+	if lineChannelID, lineSecret := os.Getenv("LINE_CHANNEL_ID"), os.Getenv("LINE_CHANNEL_SECRET"); lineChannelID != "" && lineSecret != "" {
+		lineCallbackURL := os.Getenv("LINE_CALLBACK_URL")
+		if lineCallbackURL == "" {
+			if isProd {
+				lineCallbackURL = "https://www.ifoodme.com/api/auth/line/callback"
+			} else {
+				lineCallbackURL = "http://localhost:3000/api/auth/line/callback"
+			}
+		}
+		log.Printf("LINE Channel ID: %s", lineChannelID)
+		log.Printf("LINE Callback URL: %s", lineCallbackURL)
+		goth.UseProviders(NewLineProvider(lineChannelID, lineSecret, lineCallbackURL))
+	}
+
+	// 预加载 Google 公钥并开启后台刷新，移动端登录无需等待首次拉取
+	getGoogleVerifier()
+
+	// 加载 OAuth 客户端登记表，配置错误时启动即失败
+	Clients()
 }
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// OAuth 客户端平台
+const (
+	PlatformWeb     = "web"
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformDev     = "dev"
+)
+
+var (
+	ErrRedirectNotAllowed = errors.New("redirect uri is not registered")
+	ErrUnknownClient      = errors.New("unknown oauth client")
+)
+
+// OAuthClient 已登记的客户端应用及其允许的登录回跳地址。
+// RedirectURIs 支持完整地址（如 https://www.ifoodme.com/）和自定义 scheme（如 travelview-dev://）
+type OAuthClient struct {
+	ClientID     string   `json:"client_id"`
+	Name         string   `json:"name"`
+	Platform     string   `json:"platform"`
+	RedirectURIs []string `json:"redirect_uris"`
+}
+
+// ClientRegistry OAuth 客户端登记表
+type ClientRegistry struct {
+	clients []OAuthClient
+}
+
+// NewClientRegistry 根据客户端列表创建登记表，登记地址不合法时返回错误
+func NewClientRegistry(clients []OAuthClient) (*ClientRegistry, error) {
+	seen := make(map[string]bool)
+	for _, client := range clients {
+		if client.ClientID == "" {
+			return nil, errors.New("oauth client_id is required")
+		}
+		if seen[client.ClientID] {
+			return nil, fmt.Errorf("duplicate oauth client_id %q", client.ClientID)
+		}
+		seen[client.ClientID] = true
+		for _, registered := range client.RedirectURIs {
+			if _, err := parseRegisteredURI(registered); err != nil {
+				return nil, fmt.Errorf("client %s: %w", client.ClientID, err)
+			}
+		}
+	}
+	return &ClientRegistry{clients: clients}, nil
+}
+
+// List 返回所有已登记的客户端
+func (r *ClientRegistry) List() []OAuthClient {
+	return r.clients
+}
+
+// Client 按 client_id 查找客户端
+func (r *ClientRegistry) Client(clientID string) (*OAuthClient, bool) {
+	for i := range r.clients {
+		if r.clients[i].ClientID == clientID {
+			return &r.clients[i], true
+		}
+	}
+	return nil, false
+}
+
+// ResolveRedirect 校验回跳地址。clientID 不为空时只匹配该客户端，
+// 否则匹配任一客户端，返回命中的客户端
+func (r *ClientRegistry) ResolveRedirect(clientID string, redirectURI string) (*OAuthClient, error) {
+	if clientID != "" {
+		client, ok := r.Client(clientID)
+		if !ok {
+			return nil, ErrUnknownClient
+		}
+		if client.Allows(redirectURI) {
+			return client, nil
+		}
+		return nil, ErrRedirectNotAllowed
+	}
+	for i := range r.clients {
+		if r.clients[i].Allows(redirectURI) {
+			return &r.clients[i], nil
+		}
+	}
+	return nil, ErrRedirectNotAllowed
+}
+
+// Allows 判断回跳地址是否属于该客户端
+func (c *OAuthClient) Allows(redirectURI string) bool {
+	for _, registered := range c.RedirectURIs {
+		if MatchRedirectURI(registered, redirectURI) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRegisteredURI 解析登记地址，只允许绝对地址且不能带 fragment
+func parseRegisteredURI(registered string) (*url.URL, error) {
+	u, err := url.Parse(registered)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect uri %q: %w", registered, err)
+	}
+	if u.Scheme == "" || u.Fragment != "" || u.User != nil {
+		return nil, fmt.Errorf("invalid redirect uri %q", registered)
+	}
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == "" {
+		return nil, fmt.Errorf("invalid redirect uri %q: host is required", registered)
+	}
+	return u, nil
+}
+
+// MatchRedirectURI 判断回跳地址是否命中登记地址。匹配规则：
+//   - scheme 必须一致（不区分大小写）
+//   - host 和端口必须完全一致（不区分大小写），不做子域名或后缀匹配
+//   - 登记地址的路径是前缀，且只能在 "/" 边界处匹配：/app 允许 /app、/app/x，不允许 /apple
+//   - 只登记 scheme 的自定义地址（如 travelview-dev://）允许该 scheme 下任意地址
+//   - 回跳地址不能带 fragment、用户信息、反斜杠或 ".." 路径段；查询参数不参与匹配
+func MatchRedirectURI(registered string, redirectURI string) bool {
+	reg, err := parseRegisteredURI(registered)
+	if err != nil {
+		return false
+	}
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Scheme == "" || u.Fragment != "" || u.User != nil || u.Opaque != "" {
+		return false
+	}
+	if !strings.EqualFold(reg.Scheme, u.Scheme) {
+		return false
+	}
+	if strings.Contains(redirectURI, "\\") || hasDotSegment(u.EscapedPath()) {
+		return false
+	}
+
+	// 自定义 scheme 只登记了 scheme 本身
+	if reg.Host == "" && (reg.Path == "" || reg.Path == "/") {
+		return true
+	}
+	if !strings.EqualFold(reg.Host, u.Host) {
+		return false
+	}
+	return matchPathPrefix(reg.Path, u.Path)
+}
+
+// matchPathPrefix 在路径段边界上做前缀匹配
+func matchPathPrefix(prefix string, p string) bool {
+	if prefix == "" || prefix == "/" {
+		return true
+	}
+	if p == prefix {
+		return true
+	}
+	if strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(p, prefix)
+	}
+	return strings.HasPrefix(p, prefix+"/")
+}
+
+// hasDotSegment 判断路径中是否包含 "." 或 ".." 段（含百分号编码形式）
+func hasDotSegment(p string) bool {
+	unescaped, err := url.PathUnescape(p)
+	if err != nil {
+		return true
+	}
+	for _, seg := range strings.Split(unescaped, "/") {
+		if seg == "." || seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultOAuthClients 未配置 OAUTH_CLIENTS_FILE 时的默认登记表
+func defaultOAuthClients() []OAuthClient {
+	web := OAuthClient{
+		ClientID: "web",
+		Name:     "ifoodme Web",
+		Platform: PlatformWeb,
+		RedirectURIs: []string{
+			"https://www.ifoodme.com/",
+			"https://ifoodme.com/",
+		},
+	}
+	if frontendURL := os.Getenv("FRONTEND_URL"); frontendURL != "" {
+		web.RedirectURIs = append(web.RedirectURIs, frontendURL)
+	}
+
+	clients := []OAuthClient{
+		web,
+		{ClientID: "ios", Name: "TravelView iOS", Platform: PlatformIOS, RedirectURIs: []string{"travelview://"}},
+		{ClientID: "android", Name: "TravelView Android", Platform: PlatformAndroid, RedirectURIs: []string{"travelview://"}},
+		{ClientID: "dev", Name: "TravelView 开发版", Platform: PlatformDev, RedirectURIs: []string{"travelview-dev://"}},
+	}
+	if os.Getenv("ENVIRONMENT") != "production" {
+		clients = append(clients, OAuthClient{
+			ClientID:     "web-dev",
+			Name:         "本地开发前端",
+			Platform:     PlatformDev,
+			RedirectURIs: []string{"http://localhost:3001/", "http://localhost:3000/"},
+		})
+	}
+	return clients
+}
+
+var (
+	clientRegistryOnce sync.Once
+	clientRegistry     *ClientRegistry
+)
+
+// Clients 返回全局 OAuth 客户端登记表。
+// OAUTH_CLIENTS_FILE 指向 JSON 文件（OAuthClient 数组）时使用该文件，否则使用默认登记表
+func Clients() *ClientRegistry {
+	clientRegistryOnce.Do(func() {
+		clients := defaultOAuthClients()
+		if file := os.Getenv("OAUTH_CLIENTS_FILE"); file != "" {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				log.Fatalf("读取 OAUTH_CLIENTS_FILE 失败: %v", err)
+			}
+			clients = nil
+			if err := json.Unmarshal(data, &clients); err != nil {
+				log.Fatalf("解析 OAUTH_CLIENTS_FILE 失败: %v", err)
+			}
+		}
+		registry, err := NewClientRegistry(clients)
+		if err != nil {
+			log.Fatalf("OAuth 客户端登记表无效: %v", err)
+		}
+		clientRegistry = registry
+	})
+	return clientRegistry
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchRedirectURI(t *testing.T) {
+	tests := []struct {
+		name       string
+		registered string
+		redirect   string
+		want       bool
+	}{
+		{"完全一致", "https://app.example.com/cb", "https://app.example.com/cb", true},
+		{"路径段前缀", "https://app.example.com/cb", "https://app.example.com/cb/done", true},
+		{"非路径段边界", "https://app.example.com/cb", "https://app.example.com/cbevil", false},
+		{"登记地址以斜杠结尾", "https://app.example.com/cb/", "https://app.example.com/cb/x", true},
+		{"登记地址以斜杠结尾不匹配无斜杠", "https://app.example.com/cb/", "https://app.example.com/cb", false},
+		{"根路径允许任意路径", "https://www.example.com/", "https://www.example.com/articles/1", true},
+		{"查询参数不参与匹配", "https://app.example.com/cb", "https://app.example.com/cb?x=1", true},
+		{"scheme 不区分大小写", "https://app.example.com/cb", "HTTPS://app.example.com/cb", true},
+		{"host 不区分大小写", "https://app.example.com/cb", "https://APP.example.com/cb", true},
+
+		{"host 不一致", "https://app.example.com/cb", "https://evil.com/cb", false},
+		{"子域名", "https://example.com/", "https://evil.example.com/", false},
+		{"host 后缀", "https://example.com/", "https://example.com.evil.com/", false},
+		{"端口不一致", "https://app.example.com/cb", "https://app.example.com:8443/cb", false},
+		{"scheme 不一致", "https://app.example.com/cb", "http://app.example.com/cb", false},
+
+		{"自定义 scheme", "travelview://", "travelview://auth/callback", true},
+		{"自定义 scheme 不一致", "travelview://", "travelview-dev://auth/callback", false},
+		{"自定义 scheme 前缀", "travelview://", "travelviewevil://auth", false},
+		{"自定义 scheme 带 host", "myapp://callback", "myapp://callback/ok", true},
+		{"自定义 scheme host 不一致", "myapp://callback", "myapp://other", false},
+
+		{"点路径段", "https://app.example.com/cb", "https://app.example.com/cb/../admin", false},
+		{"单点路径段", "https://app.example.com/cb", "https://app.example.com/cb/./x", false},
+		{"编码的点路径段", "https://app.example.com/cb", "https://app.example.com/cb/%2e%2e/admin", false},
+		{"反斜杠", "https://app.example.com/cb", "https://app.example.com/cb\\..\\admin", false},
+		{"反斜杠改变 host", "https://app.example.com/", "https://app.example.com\\@evil.com/", false},
+		{"fragment", "https://app.example.com/cb", "https://app.example.com/cb#token", false},
+		{"用户信息", "https://good.example.com/", "https://good.example.com@evil.com/", false},
+		{"用户信息 host 一致", "https://good/", "https://good@evil/", false},
+		{"用户信息与 host 相同", "https://good.example.com/", "https://user@good.example.com/", false},
+
+		{"相对地址", "https://app.example.com/cb", "/cb", false},
+		{"协议相对地址", "https://app.example.com/cb", "//app.example.com/cb", false},
+		{"javascript", "https://app.example.com/", "javascript:alert(1)", false},
+		{"登记地址无效", "not a uri", "https://app.example.com/", false},
+		{"登记地址缺少 host", "https:///cb", "https://app.example.com/cb", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchRedirectURI(tt.registered, tt.redirect); got != tt.want {
+				t.Errorf("MatchRedirectURI(%q, %q) = %v, want %v", tt.registered, tt.redirect, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRedirect(t *testing.T) {
+	registry, err := NewClientRegistry([]OAuthClient{
+		{ClientID: "web", Platform: PlatformWeb, RedirectURIs: []string{"https://www.example.com/"}},
+		{ClientID: "ios", Platform: PlatformIOS, RedirectURIs: []string{"travelview://"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		clientID   string
+		redirect   string
+		wantClient string
+		wantErr    error
+	}{
+		{"指定客户端命中", "web", "https://www.example.com/login", "web", nil},
+		{"不指定客户端按地址查找", "", "travelview://auth", "ios", nil},
+		{"地址属于其他客户端", "web", "travelview://auth", "", ErrRedirectNotAllowed},
+		{"未登记的客户端", "android", "travelview://auth", "", ErrUnknownClient},
+		{"未登记的地址", "", "https://evil.com/", "", ErrRedirectNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := registry.ResolveRedirect(tt.clientID, tt.redirect)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && client.ClientID != tt.wantClient {
+				t.Errorf("client = %s, want %s", client.ClientID, tt.wantClient)
+			}
+		})
+	}
+}
+
+func TestNewClientRegistryRejectsInvalidURIs(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients []OAuthClient
+	}{
+		{"缺少 client_id", []OAuthClient{{RedirectURIs: []string{"https://a.example.com/"}}}},
+		{"重复 client_id", []OAuthClient{{ClientID: "a"}, {ClientID: "a"}}},
+		{"相对地址", []OAuthClient{{ClientID: "a", RedirectURIs: []string{"/cb"}}}},
+		{"带 fragment", []OAuthClient{{ClientID: "a", RedirectURIs: []string{"https://a.example.com/#x"}}}},
+		{"带用户信息", []OAuthClient{{ClientID: "a", RedirectURIs: []string{"https://u@a.example.com/"}}}},
+		{"https 缺少 host", []OAuthClient{{ClientID: "a", RedirectURIs: []string{"https:///cb"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClientRegistry(tt.clients); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
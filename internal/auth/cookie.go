@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 浏览器 Cookie 会话模式：access token 和 refresh token 保存在 HttpOnly Cookie 中，
+// 写操作需在 X-CSRF-Token 请求头中回传 csrf_token Cookie 的值（double-submit）
+const (
+	AccessCookieName  = "access_token"
+	RefreshCookieName = "refresh_token"
+	CSRFCookieName    = "csrf_token"
+	CSRFHeader        = "X-CSRF-Token"
+
+	// SessionModeHeader 登录、刷新时携带 "X-Session-Mode: cookie" 即使用 Cookie 会话模式
+	SessionModeHeader = "X-Session-Mode"
+	SessionModeCookie = "cookie"
+
+	// refreshCookiePath refresh token Cookie 只发送给认证接口
+	refreshCookiePath = "/api/auth"
+)
+
+// cookieSettings 会话 Cookie 的公共属性
+type cookieSettings struct {
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+// sessionCookieSettings 根据环境变量生成 Cookie 属性。
+// COOKIE_SAMESITE 可选 lax（默认）、strict、none；none 或生产环境时设置 Secure
+func sessionCookieSettings() cookieSettings {
+	isProd := os.Getenv("ENVIRONMENT") == "production"
+	s := cookieSettings{Secure: isProd, SameSite: http.SameSiteLaxMode}
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "strict":
+		s.SameSite = http.SameSiteStrictMode
+	case "none":
+		s.SameSite = http.SameSiteNoneMode
+		s.Secure = true
+	}
+	s.Domain = os.Getenv("COOKIE_DOMAIN")
+	if s.Domain == "" && isProd {
+		s.Domain = ".ifoodme.com"
+	}
+	return s
+}
+
+// WantsCookieSession 判断客户端是否请求 Cookie 会话模式
+func WantsCookieSession(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get(SessionModeHeader), SessionModeCookie)
+}
+
+// ExtractAccessToken 读取请求中的 access token：优先 Authorization: Bearer，其次 access_token Cookie。
+// fromCookie 为 true 时调用方需对写操作校验 CSRF
+func ExtractAccessToken(r *http.Request) (token string, fromCookie bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if strings.HasPrefix(header, "Bearer ") {
+			return strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")), false
+		}
+		return "", false
+	}
+	if cookie, err := r.Cookie(AccessCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, true
+	}
+	return "", false
+}
+
+// RefreshTokenFromCookie 读取 refresh_token Cookie
+func RefreshTokenFromCookie(r *http.Request) string {
+	if cookie, err := r.Cookie(RefreshCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// CheckCSRF 校验 X-CSRF-Token 请求头与 csrf_token Cookie 一致
+func CheckCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(CSRFCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) == 1
+}
+
+// SetSessionCookies 写入 access token、refresh token 和新的 CSRF token Cookie，返回 CSRF token
+func SetSessionCookies(w http.ResponseWriter, accessToken string, accessTTL time.Duration, refreshToken string, refreshTTL time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	csrf := base64.RawURLEncoding.EncodeToString(b)
+
+	s := sessionCookieSettings()
+	setCookie(w, s, AccessCookieName, accessToken, "/", accessTTL, true)
+	setCookie(w, s, RefreshCookieName, refreshToken, refreshCookiePath, refreshTTL, true)
+	// CSRF token 需要被前端 JS 读取，不能设置 HttpOnly；有效期与会话一致
+	setCookie(w, s, CSRFCookieName, csrf, "/", refreshTTL, false)
+	return csrf, nil
+}
+
+// ClearSessionCookies 清除会话 Cookie
+func ClearSessionCookies(w http.ResponseWriter) {
+	s := sessionCookieSettings()
+	setCookie(w, s, AccessCookieName, "", "/", -1, true)
+	setCookie(w, s, RefreshCookieName, "", refreshCookiePath, -1, true)
+	setCookie(w, s, CSRFCookieName, "", "/", -1, false)
+}
+
+// setCookie ttl 小于 0 时删除 Cookie
+func setCookie(w http.ResponseWriter, s cookieSettings, name, value, path string, ttl time.Duration, httpOnly bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   s.Domain,
+		HttpOnly: httpOnly,
+		Secure:   s.Secure,
+		SameSite: s.SameSite,
+	}
+	if ttl < 0 {
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(0, 0)
+	} else {
+		cookie.MaxAge = int(ttl.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Denylist access token 黑名单。
+// access token 有效期很短，条目只需保留到该时段内签发的 token 全部过期即可
+type Denylist interface {
+	// Revoke 使 key 对应、签发时间早于 before 所在整秒的 token 失效，条目保留到 until。
+	// JWT 的 iat 只精确到秒，与撤销同一秒签发的 token（如重置密码后立即登录）不受影响
+	Revoke(key string, before time.Time, until time.Time)
+	// IsRevoked 判断签发时间为 issuedAt 的 token 是否已被 key 撤销
+	IsRevoked(key string, issuedAt time.Time) bool
+}
+
+// AccessDenylist 全局 access token 黑名单，默认使用进程内存实现
+var AccessDenylist Denylist = NewMemoryDenylist()
+
+// TokenKey 按 jti 撤销单个 access token
+func TokenKey(jti string) string { return "jti:" + jti }
+
+// SessionKey 按会话(refresh token 家族)撤销 access token
+func SessionKey(sessionID string) string { return "sid:" + sessionID }
+
+// UserKey 撤销某个用户的全部 access token
+func UserKey(userID int) string { return fmt.Sprintf("user:%d", userID) }
+
+type denyEntry struct {
+	before time.Time
+	until  time.Time
+}
+
+// MemoryDenylist 基于内存的黑名单，适用于单实例部署
+type MemoryDenylist struct {
+	mu      sync.RWMutex
+	entries map[string]denyEntry
+}
+
+// NewMemoryDenylist 创建内存黑名单
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{entries: make(map[string]denyEntry)}
+}
+
+// Revoke 添加黑名单条目，同一 key 保留更晚的截止时间
+func (d *MemoryDenylist) Revoke(key string, before time.Time, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for k, e := range d.entries {
+		if now.After(e.until) {
+			delete(d.entries, k)
+		}
+	}
+
+	before = before.Truncate(time.Second)
+	if e, ok := d.entries[key]; ok {
+		if e.before.After(before) {
+			before = e.before
+		}
+		if e.until.After(until) {
+			until = e.until
+		}
+	}
+	d.entries[key] = denyEntry{before: before, until: until}
+}
+
+// IsRevoked 判断 token 是否已被撤销
+func (d *MemoryDenylist) IsRevoked(key string, issuedAt time.Time) bool {
+	d.mu.RLock()
+	e, ok := d.entries[key]
+	d.mu.RUnlock()
+	if !ok || time.Now().After(e.until) {
+		return false
+	}
+	return issuedAt.Before(e.before)
+}
+
+// IsAccessTokenRevoked 依次按 jti、会话和用户检查 access token 是否已被撤销
+func IsAccessTokenRevoked(jti string, sessionID string, userID int, issuedAt time.Time) bool {
+	if jti != "" && AccessDenylist.IsRevoked(TokenKey(jti), issuedAt) {
+		return true
+	}
+	if sessionID != "" && AccessDenylist.IsRevoked(SessionKey(sessionID), issuedAt) {
+		return true
+	}
+	return AccessDenylist.IsRevoked(UserKey(userID), issuedAt)
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"ar-backend/pkg/jwks"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var errIDTokenInvalid = errors.New("id token invalid")
+
+// IDTokenVerifier 使用第三方的 JWKS 校验 OpenID Connect ID Token（签名、iss、aud、exp）
+type IDTokenVerifier struct {
+	Issuers   []string
+	Audiences []string
+	Keys      *jwks.RemoteKeySet
+}
+
+// Verify 校验 ID Token 并返回其载荷
+func (v *IDTokenVerifier) Verify(rawToken string) (jwt.MapClaims, error) {
+	if len(v.Audiences) == 0 {
+		return nil, fmt.Errorf("%w: 未配置允许的 client id", errIDTokenInvalid)
+	}
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(rawToken, claims, v.Keys.Keyfunc)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", errIDTokenInvalid, err)
+	}
+	if !claims.VerifyExpiresAt(jwt.TimeFunc().Unix(), true) {
+		return nil, fmt.Errorf("%w: 缺少 exp", errIDTokenInvalid)
+	}
+
+	iss, _ := claims["iss"].(string)
+	if !contains(v.Issuers, iss) {
+		return nil, fmt.Errorf("%w: iss 不匹配 (%s)", errIDTokenInvalid, iss)
+	}
+	audOK := false
+	for _, aud := range v.Audiences {
+		if claims.VerifyAudience(aud, true) {
+			audOK = true
+			break
+		}
+	}
+	if !audOK {
+		return nil, fmt.Errorf("%w: aud 不匹配", errIDTokenInvalid)
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, fmt.Errorf("%w: 缺少 sub", errIDTokenInvalid)
+	}
+	return claims, nil
+}
+
+// claimBool 读取布尔型声明，兼容 Apple 返回的 "true"/"false" 字符串
+func claimBool(claims jwt.MapClaims, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// verifyNonce 校验 nonce：客户端提交原始 nonce，token 中可能是原值或其 SHA-256
+func verifyNonce(claims jwt.MapClaims, nonce string) bool {
+	if nonce == "" {
+		return true
+	}
+	got, _ := claims["nonce"].(string)
+	sum := sha256.Sum256([]byte(nonce))
+	hashed := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) == 1 ||
+		subtle.ConstantTimeCompare([]byte(got), []byte(hashed)) == 1
+}
+
+// splitEnvList 读取逗号分隔的环境变量列表
+func splitEnvList(key string) []string {
+	var list []string
+	for _, s := range strings.Split(os.Getenv(key), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
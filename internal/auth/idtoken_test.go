@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"ar-backend/pkg/jwks"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testClientID = "client-a"
+)
+
+// testIdP 模拟第三方身份提供方：持有签名私钥并通过 httptest 发布 JWKS
+type testIdP struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	server *httptest.Server
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var set jwks.KeySet
+	for kid, pub := range map[string]interface{}{"rsa1": &rsaKey.PublicKey, "ec1": &ecKey.PublicKey} {
+		k, err := jwks.NewJSONWebKey(kid, "", pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(server.Close)
+	return &testIdP{rsaKey: rsaKey, ecKey: ecKey, server: server}
+}
+
+// verifier 返回信任该 IdP 的校验器
+func (p *testIdP) verifier(issuer string, audiences ...string) *IDTokenVerifier {
+	return &IDTokenVerifier{
+		Issuers:   []string{issuer},
+		Audiences: audiences,
+		Keys:      jwks.NewRemoteKeySet(p.server.URL, time.Hour),
+	}
+}
+
+// sign 使用 RSA 私钥以 RS256 签发 kid=rsa1 的 token
+func (p *testIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	return p.signWith(t, jwt.SigningMethodRS256, "rsa1", p.rsaKey, claims)
+}
+
+func (p *testIdP) signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// validClaims 返回一组可以通过校验的声明，extra 覆盖或追加字段（值为 nil 时删除）
+func validClaims(extra jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": testIssuer,
+		"aud": testClientID,
+		"sub": "subject-1",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func TestIDTokenVerifierVerify(t *testing.T) {
+	idp := newTestIdP(t)
+	v := idp.verifier(testIssuer, "client-b", testClientID)
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{"有效 token", func() string { return idp.sign(t, validClaims(nil)) }, false},
+		{"aud 为数组", func() string {
+			return idp.sign(t, validClaims(jwt.MapClaims{"aud": []string{"other", testClientID}}))
+		}, false},
+		{"EC 签名", func() string {
+			return idp.signWith(t, jwt.SigningMethodES256, "ec1", idp.ecKey, validClaims(nil))
+		}, false},
+
+		{"iss 不匹配", func() string {
+			return idp.sign(t, validClaims(jwt.MapClaims{"iss": "https://evil.example.com"}))
+		}, true},
+		{"aud 不匹配", func() string { return idp.sign(t, validClaims(jwt.MapClaims{"aud": "client-x"})) }, true},
+		{"已过期", func() string {
+			return idp.sign(t, validClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}))
+		}, true},
+		{"缺少 exp", func() string { return idp.sign(t, validClaims(jwt.MapClaims{"exp": nil})) }, true},
+		{"缺少 sub", func() string { return idp.sign(t, validClaims(jwt.MapClaims{"sub": nil})) }, true},
+		{"未知 kid", func() string {
+			return idp.signWith(t, jwt.SigningMethodRS256, "unknown", idp.rsaKey, validClaims(nil))
+		}, true},
+		{"缺少 kid", func() string {
+			return idp.signWith(t, jwt.SigningMethodRS256, "", idp.rsaKey, validClaims(nil))
+		}, true},
+		{"算法与密钥不匹配（ES256 指向 RSA 公钥）", func() string {
+			return idp.signWith(t, jwt.SigningMethodES256, "rsa1", idp.ecKey, validClaims(nil))
+		}, true},
+		{"算法与密钥不匹配（RS256 指向 EC 公钥）", func() string {
+			return idp.signWith(t, jwt.SigningMethodRS256, "ec1", idp.rsaKey, validClaims(nil))
+		}, true},
+		{"HS256 以公钥作为密钥", func() string {
+			return idp.signWith(t, jwt.SigningMethodHS256, "rsa1", []byte("rsa1"), validClaims(nil))
+		}, true},
+		{"签名密钥不在 JWKS 中", func() string {
+			other, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return idp.signWith(t, jwt.SigningMethodRS256, "rsa1", other, validClaims(nil))
+		}, true},
+		{"格式错误", func() string { return "not.a.jwt" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(tt.token())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errIDTokenInvalid) {
+				t.Errorf("err = %v, want it to wrap errIDTokenInvalid", err)
+			}
+			if err == nil && claims["sub"] != "subject-1" {
+				t.Errorf("sub = %v, want subject-1", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestIDTokenVerifierRequiresAudiences(t *testing.T) {
+	idp := newTestIdP(t)
+	v := idp.verifier(testIssuer)
+	if _, err := v.Verify(idp.sign(t, validClaims(nil))); !errors.Is(err, errIDTokenInvalid) {
+		t.Errorf("err = %v, want errIDTokenInvalid when no audiences are configured", err)
+	}
+}
+
+// useGoogleVerifier 让 VerifyGoogleIDToken 在测试期间使用指定的校验器
+func useGoogleVerifier(t *testing.T, v *IDTokenVerifier) {
+	t.Helper()
+	googleVerifierOnce = sync.Once{}
+	googleVerifierOnce.Do(func() { googleVerifier = v })
+	t.Cleanup(func() {
+		googleVerifierOnce = sync.Once{}
+		googleVerifier = nil
+	})
+}
+
+func TestVerifyGoogleIDTokenEmailVerified(t *testing.T) {
+	idp := newTestIdP(t)
+	useGoogleVerifier(t, idp.verifier("https://accounts.google.com", testClientID))
+
+	tests := []struct {
+		name          string
+		emailVerified interface{}
+		wantErr       bool
+	}{
+		{"已验证", true, false},
+		{"未验证", false, true},
+		{"缺少 email_verified", nil, true},
+		{"字符串 true", "true", false},
+		{"字符串 false", "false", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := idp.sign(t, validClaims(jwt.MapClaims{
+				"iss":            "https://accounts.google.com",
+				"email":          "user@example.com",
+				"email_verified": tt.emailVerified,
+			}))
+			identity, err := VerifyGoogleIDToken(token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyGoogleIDToken err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (identity.Subject != "subject-1" || identity.Email != "user@example.com") {
+				t.Errorf("identity = %+v", identity)
+			}
+		})
+	}
+}
+
+func TestVerifyNonce(t *testing.T) {
+	sum := sha256.Sum256([]byte("raw-nonce"))
+	hashed := hex.EncodeToString(sum[:])
+	tests := []struct {
+		name  string
+		claim interface{}
+		nonce string
+		want  bool
+	}{
+		{"原始 nonce", "raw-nonce", "raw-nonce", true},
+		{"SHA-256 nonce", hashed, "raw-nonce", true},
+		{"未提交 nonce", "raw-nonce", "", true},
+		{"不匹配", "other", "raw-nonce", false},
+		{"token 中缺少 nonce", nil, "raw-nonce", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.MapClaims{}
+			if tt.claim != nil {
+				claims["nonce"] = tt.claim
+			}
+			if got := verifyNonce(claims, tt.nonce); got != tt.want {
+				t.Errorf("verifyNonce = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/markbates/goth"
+	"golang.org/x/oauth2"
+)
+
+const (
+	defaultLineAuthURL   = "https://access.line.me/oauth2/v2.1/authorize"
+	defaultLineTokenURL  = "https://api.line.me/oauth2/v2.1/token"
+	defaultLineVerifyURL = "https://api.line.me/oauth2/v2.1/verify"
+)
+
+// LineIdentity 从 LINE ID Token 中解析出的用户信息。
+// LINE 不提供 email_verified，邮箱只用于展示，不参与按邮箱自动关联
+type LineIdentity struct {
+	Subject string
+	Email   string
+	Name    string
+	Picture string
+}
+
+// lineEndpoint 读取 LINE 接口地址，测试时可通过环境变量指向本地服务
+func lineEndpoint(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// lineChannelIDs 允许的 LINE Login Channel ID：LINE_CHANNEL_IDS（逗号分隔）加上 LINE_CHANNEL_ID
+func lineChannelIDs() []string {
+	ids := splitEnvList("LINE_CHANNEL_IDS")
+	if id := os.Getenv("LINE_CHANNEL_ID"); id != "" && !contains(ids, id) {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+var lineHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// VerifyLineIDToken 通过 LINE 的 verify 接口校验 ID Token（签名、iss、aud、exp、nonce），nonce 为空时不校验。
+// 先从未校验的载荷中取出 aud，确认属于已配置的 Channel 后再以该 Channel ID 调用 verify 接口
+func VerifyLineIDToken(idToken string, nonce string) (*LineIdentity, error) {
+	channels := lineChannelIDs()
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("%w: 未配置允许的 channel id", errIDTokenInvalid)
+	}
+	unverified := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(idToken, unverified); err != nil {
+		return nil, fmt.Errorf("%w: %v", errIDTokenInvalid, err)
+	}
+	channelID := ""
+	for _, id := range channels {
+		if unverified.VerifyAudience(id, true) {
+			channelID = id
+			break
+		}
+	}
+	if channelID == "" {
+		return nil, fmt.Errorf("%w: aud 不匹配", errIDTokenInvalid)
+	}
+
+	form := url.Values{}
+	form.Set("id_token", idToken)
+	form.Set("client_id", channelID)
+	if nonce != "" {
+		form.Set("nonce", nonce)
+	}
+	resp, err := lineHTTPClient.PostForm(lineEndpoint("LINE_VERIFY_URL", defaultLineVerifyURL), form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Sub              string `json:"sub"`
+		Name             string `json:"name"`
+		Picture          string `json:"picture"`
+		Email            string `json:"email"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("LINE verify 响应解析失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s %s", errIDTokenInvalid, body.Error, body.ErrorDescription)
+	}
+	if body.Sub == "" {
+		return nil, fmt.Errorf("%w: 缺少 sub", errIDTokenInvalid)
+	}
+	return &LineIdentity{
+		Subject: body.Sub,
+		Email:   strings.ToLower(strings.TrimSpace(body.Email)),
+		Name:    body.Name,
+		Picture: body.Picture,
+	}, nil
+}
+
+// LineProvider LINE Login 的 goth 实现。与 goth 自带的 line provider 不同，
+// 授权、token 和 verify 地址均可配置，用户信息取自经 verify 接口校验的 ID Token
+type LineProvider struct {
+	config *oauth2.Config
+	name   string
+}
+
+var _ goth.Provider = &LineProvider{}
+
+// NewLineProvider 创建 LINE Login provider，申请 openid、profile、email 权限
+func NewLineProvider(channelID, channelSecret, callbackURL string) *LineProvider {
+	return &LineProvider{
+		name: "line",
+		config: &oauth2.Config{
+			ClientID:     channelID,
+			ClientSecret: channelSecret,
+			RedirectURL:  callbackURL,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   lineEndpoint("LINE_AUTH_URL", defaultLineAuthURL),
+				TokenURL:  lineEndpoint("LINE_TOKEN_URL", defaultLineTokenURL),
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+			Scopes: []string{"openid", "profile", "email"},
+		},
+	}
+}
+
+func (p *LineProvider) Name() string        { return p.name }
+func (p *LineProvider) SetName(name string) { p.name = name }
+func (p *LineProvider) Debug(bool)          {}
+
+// BeginAuth 生成 LINE 授权地址
+func (p *LineProvider) BeginAuth(state string) (goth.Session, error) {
+	return &LineSession{AuthURL: p.config.AuthCodeURL(state)}, nil
+}
+
+// UnmarshalSession 从 gothic session 中恢复 LineSession
+func (p *LineProvider) UnmarshalSession(data string) (goth.Session, error) {
+	s := &LineSession{}
+	err := json.NewDecoder(strings.NewReader(data)).Decode(s)
+	return s, err
+}
+
+// FetchUser 校验授权得到的 ID Token 并返回用户信息
+func (p *LineProvider) FetchUser(session goth.Session) (goth.User, error) {
+	s := session.(*LineSession)
+	if s.IDToken == "" {
+		return goth.User{}, errors.New("LINE 未返回 id_token，请确认已申请 openid 权限")
+	}
+	info, err := VerifyLineIDToken(s.IDToken, "")
+	if err != nil {
+		return goth.User{}, err
+	}
+	return goth.User{
+		Provider:    p.name,
+		UserID:      info.Subject,
+		Email:       info.Email,
+		Name:        info.Name,
+		NickName:    info.Name,
+		AvatarURL:   info.Picture,
+		AccessToken: s.AccessToken,
+		IDToken:     s.IDToken,
+		RawData:     map[string]interface{}{"sub": info.Subject, "email": info.Email, "name": info.Name, "picture": info.Picture},
+	}, nil
+}
+
+func (p *LineProvider) RefreshTokenAvailable() bool { return false }
+
+func (p *LineProvider) RefreshToken(string) (*oauth2.Token, error) {
+	return nil, errors.New("LINE refresh token 未启用")
+}
+
+// LineSession LINE 登录过程中保存在 gothic session 里的数据
+type LineSession struct {
+	AuthURL     string
+	AccessToken string
+	IDToken     string
+}
+
+// GetAuthURL 返回 BeginAuth 生成的授权地址
+func (s *LineSession) GetAuthURL() (string, error) {
+	if s.AuthURL == "" {
+		return "", errors.New(goth.NoAuthUrlErrorMessage)
+	}
+	return s.AuthURL, nil
+}
+
+// Authorize 用回调中的 code 换取 access token 和 ID Token
+func (s *LineSession) Authorize(provider goth.Provider, params goth.Params) (string, error) {
+	p := provider.(*LineProvider)
+	token, err := p.config.Exchange(goth.ContextForClient(lineHTTPClient), params.Get("code"))
+	if err != nil {
+		return "", err
+	}
+	if !token.Valid() {
+		return "", errors.New("LINE 返回的 token 无效")
+	}
+	s.AccessToken = token.AccessToken
+	if idToken, ok := token.Extra("id_token").(string); ok {
+		s.IDToken = idToken
+	}
+	return token.AccessToken, nil
+}
+
+// Marshal 序列化 session
+func (s *LineSession) Marshal() string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// lineVerifyServer 模拟 LINE 的 verify 接口：client_id 与 token 的 aud 一致时返回载荷，
+// 否则按 LINE 的格式返回 400。received 记录最近一次请求的表单
+func lineVerifyServer(t *testing.T, received *atomic.Value) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		received.Store(map[string][]string(r.PostForm))
+
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(r.PostForm.Get("id_token"), claims); err != nil {
+			t.Errorf("parse id_token: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !claims.VerifyAudience(r.PostForm.Get("client_id"), true) {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request", "error_description": "Invalid IdToken Audience."})
+			return
+		}
+		if nonce := r.PostForm.Get("nonce"); nonce != "" && claims["nonce"] != nonce {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_request", "error_description": "Invalid IdToken Nonce."})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(claims)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// lineToken 构造 LINE ID Token，签名由 verify 接口负责校验，这里只需格式正确
+func lineToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("channel-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifyLineIDToken(t *testing.T) {
+	var received atomic.Value
+	srv := lineVerifyServer(t, &received)
+	t.Setenv("LINE_VERIFY_URL", srv.URL)
+	t.Setenv("LINE_CHANNEL_IDS", "1111, 2222")
+	t.Setenv("LINE_CHANNEL_ID", "3333")
+
+	tests := []struct {
+		name        string
+		claims      jwt.MapClaims
+		nonce       string
+		wantChannel string // verify 接口收到的 client_id，为空表示不应调用接口
+		wantErr     bool
+	}{
+		{"第一个 Channel", jwt.MapClaims{"aud": "1111"}, "", "1111", false},
+		{"按 aud 选择第二个 Channel", jwt.MapClaims{"aud": "2222"}, "", "2222", false},
+		{"LINE_CHANNEL_ID", jwt.MapClaims{"aud": "3333"}, "", "3333", false},
+		{"nonce 一致", jwt.MapClaims{"aud": "2222", "nonce": "n-1"}, "n-1", "2222", false},
+		{"nonce 不一致", jwt.MapClaims{"aud": "2222", "nonce": "n-1"}, "n-2", "2222", true},
+		{"aud 不在配置中", jwt.MapClaims{"aud": "9999"}, "", "", true},
+		{"缺少 aud", jwt.MapClaims{}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received.Store(map[string][]string(nil))
+			claims := jwt.MapClaims{"iss": "https://access.line.me", "sub": "U123", "name": "Line User", "email": " User@Example.com "}
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+			identity, err := VerifyLineIDToken(lineToken(t, claims), tt.nonce)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyLineIDToken err = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			form, _ := received.Load().(map[string][]string)
+			gotChannel := ""
+			if form != nil {
+				gotChannel = form["client_id"][0]
+			}
+			if gotChannel != tt.wantChannel {
+				t.Errorf("client_id = %q, want %q", gotChannel, tt.wantChannel)
+			}
+
+			if err != nil {
+				if !errors.Is(err, errIDTokenInvalid) {
+					t.Errorf("err = %v, want it to wrap errIDTokenInvalid", err)
+				}
+				return
+			}
+			want := LineIdentity{Subject: "U123", Email: "user@example.com", Name: "Line User"}
+			if *identity != want {
+				t.Errorf("identity = %+v, want %+v", *identity, want)
+			}
+		})
+	}
+}
+
+func TestVerifyLineIDTokenErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_request","error_description":"IdToken expired."}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("LINE_VERIFY_URL", srv.URL)
+	t.Setenv("LINE_CHANNEL_IDS", "1111")
+
+	_, err := VerifyLineIDToken(lineToken(t, jwt.MapClaims{"aud": "1111", "sub": "U123"}), "")
+	if !errors.Is(err, errIDTokenInvalid) {
+		t.Fatalf("err = %v, want errIDTokenInvalid", err)
+	}
+	if !strings.Contains(err.Error(), "IdToken expired.") {
+		t.Errorf("err = %v, want it to include the LINE error description", err)
+	}
+}
+
+func TestVerifyLineIDTokenRejectsBadInput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"iss":"https://access.line.me","aud":"1111"}`))
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("LINE_VERIFY_URL", srv.URL)
+
+	tests := []struct {
+		name     string
+		channels string
+		token    string
+	}{
+		{"未配置 Channel", "", lineToken(t, jwt.MapClaims{"aud": "1111"})},
+		{"token 格式错误", "1111", "not-a-jwt"},
+		{"verify 响应缺少 sub", "1111", lineToken(t, jwt.MapClaims{"aud": "1111"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LINE_CHANNEL_IDS", tt.channels)
+			t.Setenv("LINE_CHANNEL_ID", "")
+			if _, err := VerifyLineIDToken(tt.token, ""); !errors.Is(err, errIDTokenInvalid) {
+				t.Errorf("err = %v, want errIDTokenInvalid", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ar-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore 登录失败计数存储。key 由 AccountAttemptKey / IPAttemptKey 生成
+type LoginAttemptStore interface {
+	// Get 返回 key 当前的失败记录，不存在时返回零值
+	Get(key string) (model.LoginAttempt, error)
+	// RecordFailure 记录一次失败并返回最新记录；距离上次失败超过 window 时重新计数
+	RecordFailure(key string, now time.Time, window time.Duration) (model.LoginAttempt, error)
+	// Lock 锁定 key 直到 until
+	Lock(key string, until time.Time) error
+	// Reset 清除 key 的失败记录和锁定
+	Reset(key string) error
+}
+
+// LoginAttempts 全局登录失败计数存储，默认使用进程内存实现，main 中可替换为数据库实现
+var LoginAttempts LoginAttemptStore = NewMemoryLoginAttemptStore()
+
+// AccountAttemptKey 按账号(邮箱)计数，不区分大小写
+func AccountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPAttemptKey 按来源 IP 计数
+func IPAttemptKey(ip string) string { return "ip:" + ip }
+
+// OTPRequestKey 按来源 IP 统计申请邮箱登录验证码的次数
+func OTPRequestKey(ip string) string { return "otp-request:" + ip }
+
+// MFAAttemptKey 按用户统计两步验证码输错次数
+func MFAAttemptKey(userID int) string { return fmt.Sprintf("mfa:%d", userID) }
+
+// LoginPolicy 登录防爆破策略
+type LoginPolicy struct {
+	Window           time.Duration // 失败计数窗口，超过该时间未再失败则重新计数
+	FreeAttempts     int           // 不需要等待的连续失败次数
+	MaxDelay         time.Duration // 递增等待时间的上限
+	AccountLockAfter int           // 账号连续失败达到该次数后锁定
+	IPLockAfter      int           // 同一 IP 失败达到该次数后锁定
+	LockDuration     time.Duration // 锁定时长
+}
+
+// envInt 读取正整数环境变量，未设置或不合法时返回默认值
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+var (
+	loginPolicyOnce sync.Once
+	loginPolicy     LoginPolicy
+)
+
+// DefaultLoginPolicy 根据环境变量生成登录策略
+func DefaultLoginPolicy() LoginPolicy {
+	loginPolicyOnce.Do(func() {
+		loginPolicy = LoginPolicy{
+			Window:           15 * time.Minute,
+			FreeAttempts:     3,
+			MaxDelay:         30 * time.Second,
+			AccountLockAfter: envInt("LOGIN_MAX_FAILURES", 10),
+			IPLockAfter:      envInt("LOGIN_IP_MAX_FAILURES", 100),
+			LockDuration:     time.Duration(envInt("LOGIN_LOCK_MINUTES", 15)) * time.Minute,
+		}
+	})
+	return loginPolicy
+}
+
+// LoginGuard 登录失败计数、递增等待和临时锁定
+type LoginGuard struct {
+	Store  LoginAttemptStore
+	Policy LoginPolicy
+}
+
+// Logins 返回使用全局存储和默认策略的 LoginGuard
+func Logins() *LoginGuard {
+	return &LoginGuard{Store: LoginAttempts, Policy: DefaultLoginPolicy()}
+}
+
+// delay 返回连续失败 failures 次后下一次尝试前需要等待的时间：1s、2s、4s……直到 MaxDelay
+func (g *LoginGuard) delay(failures int) time.Duration {
+	n := failures - g.Policy.FreeAttempts
+	if n < 0 {
+		return 0
+	}
+	if n > 16 {
+		return g.Policy.MaxDelay
+	}
+	d := time.Second << n
+	if d > g.Policy.MaxDelay {
+		return g.Policy.MaxDelay
+	}
+	return d
+}
+
+// Check 返回本次登录前还需等待的时间，0 表示允许尝试
+func (g *LoginGuard) Check(email string, ip string, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	for _, key := range []string{AccountAttemptKey(email), IPAttemptKey(ip)} {
+		attempt, err := g.Store.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			if w := attempt.LockedUntil.Sub(now); w > wait {
+				wait = w
+			}
+			continue
+		}
+		// 递增等待只作用于账号，避免共享出口 IP 的用户互相影响
+		if key == AccountAttemptKey(email) && now.Sub(attempt.LastFailedAt) < g.Policy.Window {
+			next := attempt.LastFailedAt.Add(g.delay(attempt.Failures))
+			if w := next.Sub(now); w > wait {
+				wait = w
+			}
+		}
+	}
+	return wait, nil
+}
+
+// RecordFailure 记录一次失败登录，达到阈值时锁定账号或 IP
+func (g *LoginGuard) RecordFailure(email string, ip string, now time.Time) error {
+	limits := map[string]int{
+		AccountAttemptKey(email): g.Policy.AccountLockAfter,
+		IPAttemptKey(ip):         g.Policy.IPLockAfter,
+	}
+	for key, limit := range limits {
+		attempt, err := g.Store.RecordFailure(key, now, g.Policy.Window)
+		if err != nil {
+			return err
+		}
+		if attempt.Failures >= limit {
+			if err := g.Store.Lock(key, now.Add(g.Policy.LockDuration)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSuccess 登录成功后清除账号的失败记录
+func (g *LoginGuard) RecordSuccess(email string) error {
+	return g.Store.Reset(AccountAttemptKey(email))
+}
+
+// MemoryLoginAttemptStore 基于内存的失败计数，适用于单实例部署
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]model.LoginAttempt
+}
+
+// NewMemoryLoginAttemptStore 创建内存失败计数存储
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{attempts: make(map[string]model.LoginAttempt)}
+}
+
+// Get 返回 key 当前的失败记录
+func (s *MemoryLoginAttemptStore) Get(key string) (model.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[key], nil
+}
+
+// RecordFailure 记录一次失败，同时清理已过期的条目
+func (s *MemoryLoginAttemptStore) RecordFailure(key string, now time.Time, window time.Duration) (model.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, a := range s.attempts {
+		if now.Sub(a.LastFailedAt) > window && (a.LockedUntil == nil || now.After(*a.LockedUntil)) {
+			delete(s.attempts, k)
+		}
+	}
+
+	attempt, ok := s.attempts[key]
+	if !ok || now.Sub(attempt.LastFailedAt) > window {
+		attempt = model.LoginAttempt{AttemptKey: key, LockedUntil: attempt.LockedUntil}
+	}
+	attempt.Failures++
+	attempt.LastFailedAt = now
+	s.attempts[key] = attempt
+	return attempt, nil
+}
+
+// Lock 锁定 key 直到 until
+func (s *MemoryLoginAttemptStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt := s.attempts[key]
+	attempt.AttemptKey = key
+	attempt.LockedUntil = &until
+	s.attempts[key] = attempt
+	return nil
+}
+
+// Reset 清除 key 的失败记录和锁定
+func (s *MemoryLoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+// GormLoginAttemptStore 基于 login_attempts 表的失败计数，多实例部署时共享
+type GormLoginAttemptStore struct {
+	db *gorm.DB
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewGormLoginAttemptStore 创建数据库失败计数存储
+func NewGormLoginAttemptStore(db *gorm.DB) *GormLoginAttemptStore {
+	return &GormLoginAttemptStore{db: db}
+}
+
+// Get 返回 key 当前的失败记录
+func (s *GormLoginAttemptStore) Get(key string) (model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	err := s.db.Where("attempt_key = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.LoginAttempt{}, nil
+	}
+	return attempt, err
+}
+
+// RecordFailure 以 upsert 原子地累加失败次数，多个实例并发时计数不丢失
+func (s *GormLoginAttemptStore) RecordFailure(key string, now time.Time, window time.Duration) (model.LoginAttempt, error) {
+	s.prune(now, window)
+
+	attempt := model.LoginAttempt{AttemptKey: key, Failures: 1, LastFailedAt: now}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "attempt_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":       gorm.Expr("CASE WHEN login_attempts.last_failed_at < ? THEN 1 ELSE login_attempts.failures + 1 END", now.Add(-window)),
+			"last_failed_at": now,
+		}),
+	}, clause.Returning{}).Create(&attempt).Error
+	return attempt, err
+}
+
+// Lock 锁定 key 直到 until
+func (s *GormLoginAttemptStore) Lock(key string, until time.Time) error {
+	return s.db.Model(&model.LoginAttempt{}).Where("attempt_key = ?", key).Update("locked_until", until).Error
+}
+
+// Reset 清除 key 的失败记录和锁定
+func (s *GormLoginAttemptStore) Reset(key string) error {
+	return s.db.Where("attempt_key = ?", key).Delete(&model.LoginAttempt{}).Error
+}
+
+// prune 每小时最多清理一次已过期且未锁定的记录
+func (s *GormLoginAttemptStore) prune(now time.Time, window time.Duration) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < time.Hour {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	s.db.Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-window), now).
+		Delete(&model.LoginAttempt{})
+}
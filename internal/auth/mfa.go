@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 认证方式（amr，RFC 8176），记录在 access token 和 refresh token 会话上
+const (
+	AMRPassword  = "pwd" // 邮箱密码
+	AMRFederated = "fed" // Google / Apple 等第三方登录
+	AMREmailCode = "eml" // 邮箱一次性验证码登录（非标准值）
+	AMROTP       = "otp" // TOTP 验证码
+	AMRRecovery  = "rec" // 恢复码（非标准值）
+	AMRMFA       = "mfa" // 已完成多因素认证
+)
+
+// mfaChallengeAudience 登录挑战 token 的 aud，与 access token 区分
+const mfaChallengeAudience = "mfa-challenge"
+
+// HasAMR 判断认证方式列表中是否包含 method
+func HasAMR(amr []string, method string) bool {
+	for _, m := range amr {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mfaRolesOnce sync.Once
+	mfaRoles     []string
+)
+
+// RoleRequiresMFA 判断角色是否必须完成两步验证才能执行写操作，由 MFA_REQUIRED_ROLES 配置
+func RoleRequiresMFA(role string) bool {
+	mfaRolesOnce.Do(func() {
+		mfaRoles = splitEnvList("MFA_REQUIRED_ROLES")
+	})
+	if role == "" {
+		role = RoleUser
+	}
+	return contains(mfaRoles, role)
+}
+
+// MFAIssuer 验证器应用中显示的服务名称
+func MFAIssuer() string {
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "ifoodme"
+}
+
+// getMFAEncryptionKey 从环境变量派生加密 TOTP 密钥用的 AES-256 密钥
+func getMFAEncryptionKey() []byte {
+	secret := os.Getenv("MFA_ENCRYPTION_KEY")
+	if secret == "" {
+		// 如果没有设置专门的加密密钥，使用JWT_SECRET + 后缀
+		secret = os.Getenv("JWT_SECRET") + "_mfa"
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// EncryptMFASecret 使用 AES-GCM 加密 TOTP 密钥，结果为 base64(nonce|密文)
+func EncryptMFASecret(plain string) (string, error) {
+	block, err := aes.NewCipher(getMFAEncryptionKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plain), nil)), nil
+}
+
+// DecryptMFASecret 解密 EncryptMFASecret 的结果
+func DecryptMFASecret(enc string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(getMFAEncryptionKey())
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("mfa secret 格式错误")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// MFAChallengeClaims 第一步登录成功后签发的挑战 token，只能用于 /api/auth/mfa/verify。
+// 不含 user_id 字段，无法被当作 access token 使用
+type MFAChallengeClaims struct {
+	ChallengeUserID int      `json:"mfa_uid"`
+	AMR             []string `json:"amr,omitempty"` // 第一步使用的认证方式
+	jwt.RegisteredClaims
+}
+
+// SignMFAChallenge 签发登录挑战 token
+func SignMFAChallenge(userID int, amr []string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	s := Tokens()
+	return s.Sign(&MFAChallengeClaims{
+		ChallengeUserID: userID,
+		AMR:             amr,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.Issuer(),
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+}
+
+// ParseMFAChallenge 验证登录挑战 token
+func ParseMFAChallenge(tokenStr string) (*MFAChallengeClaims, error) {
+	s := Tokens()
+	claims := &MFAChallengeClaims{}
+	token, err := s.Parse(tokenStr, claims)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+	if !claims.VerifyIssuer(s.Issuer(), true) || !claims.VerifyAudience(mfaChallengeAudience, true) || claims.ChallengeUserID == 0 {
+		return nil, ErrTokenInvalid
+	}
+	return claims, nil
+}
@@ -0,0 +1,38 @@
+package auth
+
+// 调用方类型
+const (
+	PrincipalUser   = "user"    // 通过 access token 登录的用户
+	PrincipalAPIKey = "api_key" // 通过 X-API-Key 调用的设施终端或合作方系统
+)
+
+// Principal 当前请求的调用方，由 JWTAuth 或 APIKeyAuth 写入 gin 上下文的 "principal"
+type Principal struct {
+	Type     string
+	UserID   int          // 用户调用时为用户ID，API Key 调用时为 0
+	Role     string       // 用户角色
+	AMR      []string     // 用户会话的认证方式
+	APIKeyID int          // API Key 调用时为 key_id
+	Scopes   []Permission // API Key 被授予的权限
+}
+
+// IsAPIKey 判断是否为 API Key 调用
+func (p *Principal) IsAPIKey() bool {
+	return p != nil && p.Type == PrincipalAPIKey
+}
+
+// Can 判断调用方是否拥有指定权限：用户按角色判断，API Key 按授予的权限判断
+func (p *Principal) Can(perm Permission) bool {
+	if p == nil {
+		return false
+	}
+	if p.IsAPIKey() {
+		for _, s := range p.Scopes {
+			if s == perm {
+				return true
+			}
+		}
+		return false
+	}
+	return HasPermission(p.Role, perm)
+}
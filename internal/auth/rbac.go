@@ -0,0 +1,117 @@
+package auth
+
+// 角色
+const (
+	RoleAdmin      = "admin"       // 系统管理员，拥有全部权限
+	RoleEditor     = "editor"      // 内容编辑：文章、公告、标签、设施
+	RoleStoreOwner = "store_owner" // 店铺经营者：店铺、菜单
+	RoleUser       = "user"        // 普通用户
+)
+
+// Permission 权限，格式为 "资源:操作"
+type Permission string
+
+const (
+	PermStoresRead         Permission = "stores:read" // 读权限只约束 API Key，匿名和登录用户始终可读
+	PermStoresWrite        Permission = "stores:write"
+	PermMenusRead          Permission = "menus:read"
+	PermMenusWrite         Permission = "menus:write"
+	PermFacilitiesRead     Permission = "facilities:read"
+	PermFacilitiesWrite    Permission = "facilities:write"
+	PermNoticesRead        Permission = "notices:read"
+	PermNoticesWrite       Permission = "notices:write"
+	PermTagsRead           Permission = "tags:read"
+	PermTagsWrite          Permission = "tags:write"
+	PermLanguagesRead      Permission = "languages:read"
+	PermLanguagesWrite     Permission = "languages:write"
+	PermArticlesWrite      Permission = "articles:write"  // 发布、修改自己的文章
+	PermArticlesManage     Permission = "articles:manage" // 修改、删除任何人的文章
+	PermCommentsWrite      Permission = "comments:write"
+	PermCommentsManage     Permission = "comments:manage"
+	PermFilesWrite         Permission = "files:write"
+	PermFilesManage        Permission = "files:manage"
+	PermVisitHistoryRead   Permission = "visit_history:read"
+	PermVisitHistoryWrite  Permission = "visit_history:write"
+	PermVisitHistoryManage Permission = "visit_history:manage"
+	PermUsersRead          Permission = "users:read"
+	PermUsersWrite         Permission = "users:write"
+	PermTokensManage       Permission = "tokens:manage"
+	PermAPIKeysManage      Permission = "api_keys:manage"
+	PermAuthEventsRead     Permission = "auth_events:read"
+	PermSystemManage       Permission = "system:manage"
+)
+
+// allPermissions 全部已定义的权限
+var allPermissions = []Permission{
+	PermStoresRead, PermMenusRead, PermFacilitiesRead, PermNoticesRead, PermTagsRead, PermLanguagesRead,
+	PermStoresWrite, PermMenusWrite, PermFacilitiesWrite, PermNoticesWrite, PermTagsWrite, PermLanguagesWrite,
+	PermArticlesWrite, PermArticlesManage, PermCommentsWrite, PermCommentsManage, PermFilesWrite, PermFilesManage,
+	PermVisitHistoryRead, PermVisitHistoryWrite, PermVisitHistoryManage, PermUsersRead, PermUsersWrite,
+	PermTokensManage, PermAPIKeysManage, PermAuthEventsRead, PermSystemManage,
+}
+
+// 普通用户拥有的权限，其他角色在此基础上追加
+var baseUserPermissions = []Permission{
+	PermStoresRead,
+	PermMenusRead,
+	PermFacilitiesRead,
+	PermNoticesRead,
+	PermTagsRead,
+	PermLanguagesRead,
+	PermArticlesWrite,
+	PermCommentsWrite,
+	PermFilesWrite,
+	PermVisitHistoryWrite,
+}
+
+// rolePermissions 角色到权限的映射；admin 不在表中，拥有全部权限
+var rolePermissions = map[string][]Permission{
+	RoleUser: baseUserPermissions,
+	RoleStoreOwner: append([]Permission{
+		PermStoresWrite,
+		PermMenusWrite,
+	}, baseUserPermissions...),
+	RoleEditor: append([]Permission{
+		PermArticlesManage,
+		PermCommentsManage,
+		PermFilesManage,
+		PermNoticesWrite,
+		PermTagsWrite,
+		PermFacilitiesWrite,
+	}, baseUserPermissions...),
+}
+
+// IsValidRole 判断角色名是否有效
+func IsValidRole(role string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsValidPermission 判断权限名是否已定义
+func IsValidPermission(perm Permission) bool {
+	for _, p := range allPermissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission 判断角色是否拥有指定权限，空角色按普通用户处理
+func HasPermission(role string, perm Permission) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	if role == "" {
+		role = RoleUser
+	}
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ar-backend/pkg/jwks"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const defaultTokenIssuer = "ar-backend"
+
+var (
+	ErrTokenInvalid = errors.New("token invalid")
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// AccessClaims access token 的载荷
+type AccessClaims struct {
+	UserID    int      `json:"user_id"`
+	Role      string   `json:"role,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	AMR       []string `json:"amr,omitempty"` // 认证方式，如 ["pwd","otp","mfa"]
+	jwt.RegisteredClaims
+}
+
+// signingKey 一把签名密钥；退役的密钥只保留公钥，仅用于验证
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// TokenService 使用非对称密钥签发和验证 access token。
+// 轮换期间可同时持有多把密钥：新 token 由 active 密钥签发，旧密钥签发的 token 在过期前仍可验证
+type TokenService struct {
+	issuer string
+	mu     sync.RWMutex
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var (
+	tokenServiceOnce sync.Once
+	tokenService     *TokenService
+)
+
+// Tokens 返回全局的 TokenService，首次调用时从环境变量加载密钥
+func Tokens() *TokenService {
+	tokenServiceOnce.Do(func() {
+		s, err := NewTokenServiceFromEnv()
+		if err != nil {
+			log.Fatalf("初始化 JWT 签名密钥失败: %v", err)
+		}
+		tokenService = s
+	})
+	return tokenService
+}
+
+// NewTokenServiceFromEnv 从 JWT_KEYS_DIR 加载密钥，JWT_ACTIVE_KID 指定签名密钥（默认取文件名排序最后一把）。
+// 目录中 <kid>.pem 为私钥（RSA / ECDSA / Ed25519），<kid>.pub.pem 为仅用于验证的退役公钥。
+// 未配置目录时，非生产环境生成临时 Ed25519 密钥，重启后旧 token 全部失效
+func NewTokenServiceFromEnv() (*TokenService, error) {
+	s := &TokenService{issuer: os.Getenv("JWT_ISSUER"), keys: map[string]*signingKey{}}
+	if s.issuer == "" {
+		s.issuer = defaultTokenIssuer
+	}
+
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		if os.Getenv("ENVIRONMENT") == "production" {
+			return nil, errors.New("生产环境必须设置 JWT_KEYS_DIR")
+		}
+		log.Println("⚠️ 未设置 JWT_KEYS_DIR，使用临时生成的 Ed25519 密钥（仅限开发环境）")
+		return s, s.addEphemeralKey()
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var lastPrivate string
+	for _, file := range files {
+		key, err := loadKeyFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		s.keys[key.kid] = key
+		if key.private != nil {
+			lastPrivate = key.kid
+		}
+	}
+
+	activeKid := os.Getenv("JWT_ACTIVE_KID")
+	if activeKid == "" {
+		activeKid = lastPrivate
+	}
+	active, ok := s.keys[activeKid]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("找不到可用于签名的私钥 (kid=%q)", activeKid)
+	}
+	s.active = active
+	log.Printf("JWT 签名密钥: kid=%s alg=%s，可验证密钥 %d 把", active.kid, active.method.Alg(), len(s.keys))
+	return s, nil
+}
+
+func (s *TokenService) addEphemeralKey() error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	key := &signingKey{kid: "dev-" + hex.EncodeToString(b), method: jwt.SigningMethodEdDSA, private: priv, public: pub}
+	s.keys[key.kid] = key
+	s.active = key
+	return nil
+}
+
+// loadKeyFile 读取 PEM 格式的私钥或公钥，文件名（去掉 .pem / .pub.pem）作为 kid
+func loadKeyFile(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("不是有效的 PEM 文件")
+	}
+
+	kid := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".pem"), ".pub")
+	key := &signingKey{kid: kid}
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("不支持的 PEM 类型: %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.private = signer
+		key.public = signer.Public()
+	} else {
+		key.public = parsed
+	}
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		key.method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch pub.Curve.Params().BitSize {
+		case 256:
+			key.method = jwt.SigningMethodES256
+		case 384:
+			key.method = jwt.SigningMethodES384
+		default:
+			key.method = jwt.SigningMethodES512
+		}
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型: %T", key.public)
+	}
+	return key, nil
+}
+
+// Sign 使用当前签名密钥签发 token，header 中带上 kid
+func (s *TokenService) Sign(claims jwt.Claims) (string, error) {
+	s.mu.RLock()
+	key := s.active
+	s.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Issuer 返回签发的 token 使用的 iss
+func (s *TokenService) Issuer() string {
+	return s.issuer
+}
+
+// SignAccessToken 签发 access token，补全 iss
+func (s *TokenService) SignAccessToken(claims *AccessClaims) (string, error) {
+	claims.Issuer = s.issuer
+	return s.Sign(claims)
+}
+
+// Parse 按 kid 选择公钥验证 token，并校验算法与密钥类型一致
+func (s *TokenService) Parse(tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		s.mu.RLock()
+		key, ok := s.keys[kid]
+		s.mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("未知的 kid: %s", kid)
+		}
+		if err := jwks.CheckSigningMethod(token.Method, key.public); err != nil {
+			return nil, err
+		}
+		return key.public, nil
+	})
+}
+
+// ParseAccessToken 验证 access token（签名、过期、iss）并检查是否已被撤销
+func (s *TokenService) ParseAccessToken(tokenStr string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	token, err := s.Parse(tokenStr, claims)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+	if !claims.VerifyIssuer(s.issuer, true) {
+		return nil, fmt.Errorf("%w: iss 不匹配", ErrTokenInvalid)
+	}
+	if claims.UserID == 0 {
+		return nil, fmt.Errorf("%w: 缺少 user_id", ErrTokenInvalid)
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	if IsAccessTokenRevoked(claims.ID, claims.SessionID, claims.UserID, issuedAt) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// JWKS 返回全部验证公钥，供其他服务验证 access token
+func (s *TokenService) JWKS() jwks.KeySet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	kids := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := jwks.KeySet{Keys: []jwks.JSONWebKey{}}
+	for _, kid := range kids {
+		key := s.keys[kid]
+		jwk, err := jwks.NewJSONWebKey(key.kid, key.method.Alg(), key.public)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,599 @@
+package controller
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/aws"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultDataExportTTLHours = 72 // 导出文件默认保留小时数
+	deletedCommentText        = "该评论已随账号注销删除"
+	accountDeleteReauthWindow = 10 * time.Minute // 未设置密码的账号注销前需在该时间内重新登录
+)
+
+// dataExportDir 导出文件的保存目录，由 DATA_EXPORT_DIR 配置
+func dataExportDir() string {
+	if dir := os.Getenv("DATA_EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "ar-data-exports")
+}
+
+// dataExportTTL 导出文件的保留时长，由 DATA_EXPORT_TTL_HOURS 配置
+func dataExportTTL() time.Duration {
+	hours := defaultDataExportTTLHours
+	if v, err := strconv.Atoi(os.Getenv("DATA_EXPORT_TTL_HOURS")); err == nil && v > 0 {
+		hours = v
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// ExportMyData godoc
+// @Summary 导出个人数据
+// @Description 在后台生成包含个人资料、访问记录、文章、评论、上传文件、登录会话和认证事件的 ZIP。已有进行中或未过期的导出时直接返回该任务（并发请求也只会创建一个），否则创建新任务并返回 202；完成后通过下载接口获取
+// @Tags Account
+// @Produce json
+// @Success 200 {object} model.Response[model.DataExport]
+// @Success 202 {object} model.Response[model.DataExport]
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/me/export [get]
+func ExportMyData(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	db := database.GetDB()
+	now := time.Now()
+
+	var export model.DataExport
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 锁住用户行，同一用户的并发请求在此排队，避免重复创建导出任务
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("user_id").First(&model.User{}, userID).Error; err != nil {
+			return err
+		}
+		err := tx.Where("user_id = ?", userID).Order("export_id DESC").First(&export).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == nil {
+			switch {
+			case export.Status == model.DataExportPending || export.Status == model.DataExportRunning:
+				return nil
+			case export.Status == model.DataExportCompleted && export.ExpiresAt != nil && export.ExpiresAt.After(now):
+				return nil
+			}
+		}
+		export = model.DataExport{UserID: userID, Status: model.DataExportPending}
+		created = true
+		return tx.Create(&export).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	if !created {
+		if export.Status == model.DataExportCompleted {
+			c.JSON(http.StatusOK, model.Response[model.DataExport]{Success: true, Code: 200, Data: export})
+			return
+		}
+		c.JSON(http.StatusAccepted, model.Response[model.DataExport]{Success: true, Code: 202, Data: export})
+		return
+	}
+	go runDataExport(db, export)
+	c.JSON(http.StatusAccepted, model.Response[model.DataExport]{Success: true, Code: 202, Data: export})
+}
+
+// DownloadMyDataExport godoc
+// @Summary 下载个人数据导出文件
+// @Description 下载已完成且未过期的个人数据 ZIP
+// @Tags Account
+// @Produce application/zip
+// @Param export_id path int true "导出任务ID"
+// @Success 200 {file} file
+// @Failure 404 {object} model.BaseResponse
+// @Failure 409 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/me/export/{export_id}/download [get]
+func DownloadMyDataExport(c *gin.Context) {
+	exportID, _ := strconv.Atoi(c.Param("export_id"))
+	db := database.GetDB()
+	var export model.DataExport
+	if err := db.Where("export_id = ? AND user_id = ?", exportID, c.GetInt("user_id")).First(&export).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "导出任务不存在", Code: 404})
+		return
+	}
+	if export.Status != model.DataExportCompleted {
+		c.JSON(http.StatusConflict, model.BaseResponse{Success: false, ErrMessage: "导出尚未完成", Code: 409})
+		return
+	}
+	if export.ExpiresAt == nil || !export.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "导出文件已过期，请重新导出", Code: 404})
+		return
+	}
+	if _, err := os.Stat(export.FilePath); err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "导出文件不存在，请重新导出", Code: 404})
+		return
+	}
+	c.FileAttachment(export.FilePath, fmt.Sprintf("ifoodme-data-export-%d.zip", export.ExportID))
+}
+
+// runDataExport 后台生成导出文件，先写入临时文件，完成后再改名并更新任务状态
+func runDataExport(db *gorm.DB, export model.DataExport) {
+	fail := func(err error) {
+		log.Printf("个人数据导出失败 (user_id=%d, export_id=%d): %v", export.UserID, export.ExportID, err)
+		db.Model(&model.DataExport{}).Where("export_id = ?", export.ExportID).Updates(map[string]interface{}{
+			"status": model.DataExportFailed,
+			"error":  truncate(err.Error(), 255),
+		})
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			fail(fmt.Errorf("panic: %v", r))
+		}
+	}()
+
+	if err := db.Model(&model.DataExport{}).Where("export_id = ?", export.ExportID).
+		Update("status", model.DataExportRunning).Error; err != nil {
+		fail(err)
+		return
+	}
+
+	dir := dataExportDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		fail(err)
+		return
+	}
+	filePath := filepath.Join(dir, fmt.Sprintf("user-%d-export-%d.zip", export.UserID, export.ExportID))
+	tmpPath := filePath + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		fail(err)
+		return
+	}
+	err = writeDataExport(db, export.UserID, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		fail(err)
+		return
+	}
+
+	var size int64
+	if info, err := os.Stat(filePath); err == nil {
+		size = info.Size()
+	}
+	now := time.Now()
+	result := db.Model(&model.DataExport{}).Where("export_id = ?", export.ExportID).Updates(map[string]interface{}{
+		"status":       model.DataExportCompleted,
+		"file_path":    filePath,
+		"file_size":    size,
+		"completed_at": now,
+		"expires_at":   now.Add(dataExportTTL()),
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		// 任务记录已不存在（如导出期间账号被注销），不保留导出文件
+		os.Remove(filePath)
+		if result.Error != nil {
+			fail(result.Error)
+		}
+	}
+}
+
+// dataExportManifest 导出包中的说明文件
+type dataExportManifest struct {
+	UserID       int       `json:"user_id"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	Contents     []string  `json:"contents"`
+	MissingFiles []string  `json:"missing_files,omitempty"` // 无法读取内容的上传文件及原因
+}
+
+// writeDataExport 将用户的全部个人数据写成 ZIP
+func writeDataExport(db *gorm.DB, userID int, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	manifest := dataExportManifest{UserID: userID, GeneratedAt: time.Now()}
+	addJSON := func(name string, v interface{}) error {
+		entry, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(entry)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		manifest.Contents = append(manifest.Contents, name)
+		return nil
+	}
+
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return err
+	}
+	user.Password = ""
+	if err := addJSON("profile.json", user); err != nil {
+		return err
+	}
+
+	var identities []model.UserIdentity
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return err
+	}
+	if err := addJSON("identities.json", identities); err != nil {
+		return err
+	}
+
+	var visits []model.VisitHistory
+	if err := db.Where("user_id = ?", userID).Order("scan_at").Find(&visits).Error; err != nil {
+		return err
+	}
+	if err := addJSON("visit_history.json", visits); err != nil {
+		return err
+	}
+
+	var comments []model.Comment
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&comments).Error; err != nil {
+		return err
+	}
+	if err := addJSON("comments.json", comments); err != nil {
+		return err
+	}
+
+	var articles []model.Article
+	if err := db.Where("author_id = ?", userID).Order("created_at").Find(&articles).Error; err != nil {
+		return err
+	}
+	if err := addJSON("articles.json", articles); err != nil {
+		return err
+	}
+
+	var likes []model.ArticleLike
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&likes).Error; err != nil {
+		return err
+	}
+	if err := addJSON("likes.json", likes); err != nil {
+		return err
+	}
+
+	var sessions []model.RefreshToken
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&sessions).Error; err != nil {
+		return err
+	}
+	for i := range sessions {
+		sessions[i].RefreshToken = ""
+	}
+	if err := addJSON("sessions.json", sessions); err != nil {
+		return err
+	}
+
+	var events []model.AuthEvent
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&events).Error; err != nil {
+		return err
+	}
+	if err := addJSON("auth_events.json", events); err != nil {
+		return err
+	}
+
+	var files []model.File
+	if err := db.Where("user_id = ?", userID).Order("file_id").Find(&files).Error; err != nil {
+		return err
+	}
+	var s3Service *aws.S3Service
+	var s3Err error
+	for i := range files {
+		file := &files[i]
+		data := file.FileData
+		file.FileData = nil
+		if file.S3Key != "" {
+			if s3Service == nil && s3Err == nil {
+				s3Service, s3Err = aws.NewS3Service()
+			}
+			if s3Err != nil {
+				manifest.MissingFiles = append(manifest.MissingFiles, fmt.Sprintf("%d: %v", file.FileID, s3Err))
+				continue
+			}
+			var err error
+			if data, err = s3Service.DownloadFile(file.S3Key); err != nil {
+				manifest.MissingFiles = append(manifest.MissingFiles, fmt.Sprintf("%d: %v", file.FileID, err))
+				continue
+			}
+		}
+		name := fmt.Sprintf("files/%d_%s", file.FileID, path.Base(strings.ReplaceAll(file.FileName, "\\", "/")))
+		entry, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(data); err != nil {
+			return err
+		}
+		manifest.Contents = append(manifest.Contents, name)
+	}
+	if err := addJSON("files.json", files); err != nil {
+		return err
+	}
+
+	if err := addJSON("manifest.json", manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// DeleteMyAccount godoc
+// @Summary 注销账号
+// @Description 注销当前账号：个人资料匿名化，访问记录、第三方关联、两步验证、登录会话、导出文件和上传文件（含 S3 对象）被删除，评论内容被清除，认证事件去除邮箱和设备信息，创建的 API Key 被撤销，所有 token 立即失效。confirm 需填写当前账号邮箱，设置过密码的账号需提供密码，未设置密码的账号需在重新登录后 10 分钟内操作，开启两步验证的账号需使用两步验证登录
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Param payload body model.DeleteAccountRequest true "注销确认"
+// @Success 200 {object} model.BaseResponse
+// @Failure 400 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/me [delete]
+func DeleteMyAccount(c *gin.Context) {
+	var req model.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	userID := c.GetInt("user_id")
+	db := database.GetDB()
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "用户不存在", Code: 404})
+		return
+	}
+	if !strings.EqualFold(strings.TrimSpace(req.Confirm), user.Email) {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "确认邮箱与当前账号不一致", Code: 400})
+		return
+	}
+	if user.Password != "" && bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)) != nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "密码错误", Code: 401})
+		return
+	}
+	if msg, err := deleteAccountReauthError(c, db, user); err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	} else if msg != "" {
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: msg, Code: 401})
+		return
+	}
+
+	var files []model.File
+	var exports []model.DataExport
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := revokeAllUserTokens(tx, userID); err != nil {
+			return err
+		}
+		// 文章配图属于文章内容，只解除与用户的关联；其余上传文件连同 S3 对象删除
+		articleImages := tx.Model(&model.Article{}).Select("image_file_id").Where("image_file_id IS NOT NULL")
+		if err := tx.Where("user_id = ? AND file_id NOT IN (?)", userID, articleImages).Find(&files).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.File{}).Where("user_id = ?", userID).Update("user_id", nil).Error; err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			ids := make([]int, 0, len(files))
+			for _, f := range files {
+				ids = append(ids, f.FileID)
+			}
+			if err := tx.Delete(&model.File{}, ids).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("user_id = ?", userID).Find(&exports).Error; err != nil {
+			return err
+		}
+
+		// 先扣减点赞过的文章的 like_count，再删除点赞记录
+		likedArticles := tx.Model(&model.ArticleLike{}).Select("article_id").Where("user_id = ?", userID)
+		if err := tx.Model(&model.Article{}).Where("article_id IN (?)", likedArticles).
+			UpdateColumn("like_count", gorm.Expr("like_count - 1")).Error; err != nil {
+			return err
+		}
+
+		for _, m := range []interface{}{
+			&model.ArticleLike{},
+			&model.RefreshToken{},
+			&model.VisitHistory{},
+			&model.UserIdentity{},
+			&model.UserMFA{},
+			&model.MFARecoveryCode{},
+			&model.PasswordResetToken{},
+			&model.AuthorizationCode{},
+			&model.DataExport{},
+		} {
+			if err := tx.Where("user_id = ?", userID).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		// 保留评论占位，避免回复串断开
+		if err := tx.Model(&model.Comment{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"comment_text": deletedCommentText,
+			"updated_at":   now,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.APIKey{}).Where("created_by = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		// 认证事件保留用于安全统计，去除可识别个人的信息
+		if err := tx.Model(&model.AuthEvent{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"email":      "",
+			"ip_address": "",
+			"user_agent": "",
+		}).Error; err != nil {
+			return err
+		}
+		// 用户记录保留为匿名占位，评论等数据的 user_id 仍然有效
+		return tx.Model(&model.User{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"name":                "",
+			"name_kana":           "",
+			"birth":               nil,
+			"address":             "",
+			"gender":              nil,
+			"phone_number":        "",
+			"email":               fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+			"password":            "",
+			"pending_password":    "",
+			"avatar":              "",
+			"google_id":           "",
+			"apple_id":            "",
+			"status":              "deleted",
+			"verify_code":         "",
+			"verify_code_expire":  nil,
+			"verify_code_sent_at": nil,
+			"updated_at":          now,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+
+	removeS3Objects(files)
+	for _, export := range exports {
+		if export.FilePath != "" {
+			os.Remove(export.FilePath)
+		}
+	}
+	if err := auth.Logins().RecordSuccess(user.Email); err != nil {
+		log.Printf("清除登录失败次数出错: %v", err)
+	}
+	recordAuthEvent(c, db, userID, "", model.AuthEventAccountDelete, true, "")
+	auth.ClearSessionCookies(c.Writer)
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, Code: 200})
+}
+
+// deleteAccountReauthError 检查注销前的身份确认，不满足时返回提示。
+// 开启两步验证的账号，当前会话必须完成过两步验证；未设置密码的账号（第三方登录、邮箱验证码登录）
+// 无法用密码确认，当前会话必须是最近重新登录的
+func deleteAccountReauthError(c *gin.Context, db *gorm.DB, user model.User) (string, error) {
+	enabled, err := userMFAEnabled(db, user.UserID)
+	if err != nil {
+		return "", err
+	}
+	if enabled && !auth.HasAMR(c.GetStringSlice("amr"), auth.AMRMFA) {
+		return "注销账号需要使用两步验证重新登录", nil
+	}
+	if user.Password != "" {
+		return "", nil
+	}
+	authenticatedAt, err := sessionAuthenticatedAt(db, c.GetString("session_id"))
+	if err != nil {
+		return "", err
+	}
+	if authenticatedAt == nil || time.Since(*authenticatedAt) > accountDeleteReauthWindow {
+		return fmt.Sprintf("为确认是本人操作，请重新登录后在 %d 分钟内完成注销", int(accountDeleteReauthWindow.Minutes())), nil
+	}
+	return "", nil
+}
+
+// sessionAuthenticatedAt 返回会话的登录时间，即该 refresh token 家族中最早签发的时间；找不到会话时返回 nil
+func sessionAuthenticatedAt(db *gorm.DB, sessionID string) (*time.Time, error) {
+	if sessionID == "" {
+		return nil, nil
+	}
+	var first model.RefreshToken
+	err := db.Where("family_id = ?", sessionID).Order("token_id").First(&first).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &first.CreatedAt, nil
+}
+
+// removeS3Objects 删除文件对应的 S3 对象，失败只记录日志
+func removeS3Objects(files []model.File) {
+	var s3Service *aws.S3Service
+	for _, f := range files {
+		if f.S3Key == "" {
+			continue
+		}
+		if s3Service == nil {
+			var err error
+			if s3Service, err = aws.NewS3Service(); err != nil {
+				log.Printf("S3 服务初始化失败，未删除 S3 对象: %v", err)
+				return
+			}
+		}
+		if err := s3Service.DeleteFile(f.S3Key); err != nil {
+			log.Printf("删除 S3 对象失败 (file_id=%d): %v", f.FileID, err)
+		}
+	}
+}
+
+// PurgeDataExports 删除已过期的导出文件和任务记录，以及超过保留期的失败任务，返回删除条数
+func PurgeDataExports(db *gorm.DB, now time.Time) (int64, error) {
+	var exports []model.DataExport
+	if err := db.Where("expires_at < ? OR (status = ? AND created_at < ?)",
+		now, model.DataExportFailed, now.Add(-dataExportTTL())).Find(&exports).Error; err != nil {
+		return 0, err
+	}
+	if len(exports) == 0 {
+		return 0, nil
+	}
+	ids := make([]int, 0, len(exports))
+	for _, export := range exports {
+		if export.FilePath != "" {
+			if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+				log.Printf("删除导出文件失败 (export_id=%d): %v", export.ExportID, err)
+			}
+		}
+		ids = append(ids, export.ExportID)
+	}
+	result := db.Delete(&model.DataExport{}, ids)
+	return result.RowsAffected, result.Error
+}
+
+// StartDataExportCleanup 启动时把上次进程中断的导出任务标记为失败，之后每小时清理一次过期的导出文件
+func StartDataExportCleanup(db *gorm.DB) {
+	if err := db.Model(&model.DataExport{}).
+		Where("status IN ?", []string{model.DataExportPending, model.DataExportRunning}).
+		Updates(map[string]interface{}{"status": model.DataExportFailed, "error": "服务重启，导出中断"}).Error; err != nil {
+		log.Printf("重置中断的导出任务失败: %v", err)
+	}
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if n, err := PurgeDataExports(db, time.Now()); err != nil {
+				log.Printf("清理过期导出文件失败: %v", err)
+			} else if n > 0 {
+				log.Printf("已清理 %d 个过期导出任务", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
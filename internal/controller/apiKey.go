@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAPIKeys godoc
+// @Summary 获取 API Key 列表
+// @Description 管理员查看全部 API Key（不含密钥明文），包括已过期和已撤销的
+// @Tags APIKeys
+// @Produce json
+// @Success 200 {object} model.Response[[]model.APIKey]
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/api-keys [get]
+func ListAPIKeys(c *gin.Context) {
+	db := database.GetDB()
+	var keys []model.APIKey
+	if err := db.Order("created_at DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.Response[[]model.APIKey]{Success: true, Code: 200, Data: keys})
+}
+
+// CreateAPIKey godoc
+// @Summary 创建 API Key
+// @Description 为设施终端或合作方系统创建带权限范围的 API Key，调用时通过 X-API-Key 请求头携带。密钥明文只在本次响应中返回
+// @Tags APIKeys
+// @Accept json
+// @Produce json
+// @Param payload body model.APIKeyReqCreate true "API Key 信息"
+// @Success 200 {object} model.Response[model.APIKeyCreated]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/api-keys [post]
+func CreateAPIKey(c *gin.Context) {
+	var req model.APIKeyReqCreate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+
+	var scopes []string
+	seen := map[string]bool{}
+	for _, s := range req.Scopes {
+		s = strings.TrimSpace(s)
+		if !auth.IsValidAPIKeyScope(auth.Permission(s)) {
+			c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "不支持的权限范围: " + s, Code: 400})
+			return
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "API Key 生成失败", Code: 500})
+		return
+	}
+	apiKey := model.APIKey{
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    prefix,
+		KeyHash:   auth.HashAPIKey(key),
+		Scopes:    strings.Join(scopes, ","),
+		CreatedBy: c.GetInt("user_id"),
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiKey.ExpiresAt = &expires
+	}
+
+	db := database.GetDB()
+	if err := db.Create(&apiKey).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.Response[model.APIKeyCreated]{Success: true, Code: 200, Data: model.APIKeyCreated{APIKey: apiKey, Key: key}})
+}
+
+// RevokeAPIKey godoc
+// @Summary 撤销 API Key
+// @Description 撤销后该 API Key 立即失效，记录保留用于审计
+// @Tags APIKeys
+// @Produce json
+// @Param key_id path int true "API Key ID"
+// @Success 200 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/api-keys/{key_id} [delete]
+func RevokeAPIKey(c *gin.Context) {
+	keyID, _ := strconv.Atoi(c.Param("key_id"))
+	db := database.GetDB()
+	result := db.Model(&model.APIKey{}).Where("key_id = ? AND revoked_at IS NULL", keyID).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: result.Error.Error(), Code: 500})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "API Key 不存在或已撤销", Code: 404})
+		return
+	}
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, Code: 200})
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// appleDisplayName 拼接 Apple 返回的姓名（姓在前，与日文表记一致）
+func appleDisplayName(info *model.AppleUserInfo) string {
+	if info == nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimSpace(info.Name.LastName) + " " + strings.TrimSpace(info.Name.FirstName))
+}
+
+// AppleAuth godoc
+// @Summary Sign in with Apple 登录
+// @Description 校验 Apple identity token，按关联身份查找用户；首次登录时按已验证邮箱关联已有账号或创建新用户
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.AppleAuthRequest true "Apple 登录请求"
+// @Success 200 {object} model.Response[model.AuthResponse]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 409 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/apple [post]
+func AppleAuth(c *gin.Context) {
+	var req model.AppleAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+
+	ext, err := verifyProviderIDToken("apple", req.IdToken, req.Nonce)
+	if err != nil {
+		log.Printf("❌ Apple token验证失败: %v", err)
+		recordAuthEvent(c, database.GetDB(), 0, "", model.AuthEventLoginFailed, false, "apple: invalid token")
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "Apple token无效", Code: 400})
+		return
+	}
+	ext.Name = appleDisplayName(req.User)
+
+	db := database.GetDB()
+	user, err := ResolveIdentityUser(db, ext)
+	if errors.Is(err, ErrIdentityEmailConflict) {
+		recordAuthEvent(c, db, 0, ext.Email, model.AuthEventLoginFailed, false, "apple: email_conflict")
+		c.JSON(http.StatusConflict, model.BaseResponse{Success: false, ErrMessage: "该邮箱已注册，请登录后在账号设置中关联 Apple", Code: 409})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Apple用户登录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "用户注册失败", Code: 500})
+		return
+	}
+
+	completeLogin(c, db, user, auth.AMRFederated)
+}
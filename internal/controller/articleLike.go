@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errArticleNotVisible = errors.New("article not visible")
+
+// LikeArticle godoc
+// @Summary 点赞文章
+// @Description 当前用户点赞文章，重复点赞不会重复计数。只能点赞自己可见的文章
+// @Tags Articles
+// @Produce json
+// @Param article_id path int true "文章ID"
+// @Success 200 {object} model.Response[model.ArticleLikeState]
+// @Failure 401 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/articles/{article_id}/like [put]
+func LikeArticle(c *gin.Context) {
+	setArticleLike(c, true)
+}
+
+// UnlikeArticle godoc
+// @Summary 取消点赞文章
+// @Description 当前用户取消点赞，未点赞时直接返回当前状态
+// @Tags Articles
+// @Produce json
+// @Param article_id path int true "文章ID"
+// @Success 200 {object} model.Response[model.ArticleLikeState]
+// @Failure 401 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/articles/{article_id}/like [delete]
+func UnlikeArticle(c *gin.Context) {
+	setArticleLike(c, false)
+}
+
+// setArticleLike 写入或删除点赞记录，仅在记录实际变化时更新 like_count，保证接口幂等
+func setArticleLike(c *gin.Context, liked bool) {
+	articleID, _ := strconv.Atoi(c.Param("article_id"))
+	userID := c.GetInt("user_id")
+
+	db := database.GetDB()
+	var article model.Article
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockArticle(tx, articleID, &article); err != nil {
+			return err
+		}
+		var result *gorm.DB
+		delta := 1
+		if liked {
+			if !canViewArticle(c, article) {
+				return errArticleNotVisible
+			}
+			like := model.ArticleLike{ArticleID: articleID, UserID: userID}
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&like)
+		} else {
+			delta = -1
+			result = tx.Where("article_id = ? AND user_id = ?", articleID, userID).Delete(&model.ArticleLike{})
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Model(&article).UpdateColumn("like_count", gorm.Expr("like_count + ?", delta)).Error; err != nil {
+			return err
+		}
+		return tx.First(&article, articleID).Error
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound || err == errArticleNotVisible:
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在", Code: 404})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.Response[model.ArticleLikeState]{Success: true, Code: 200, Data: model.ArticleLikeState{
+		ArticleID: article.ArticleID,
+		Liked:     liked,
+		LikeCount: article.LikeCount,
+	}})
+}
+
+// RecountArticleLikes 按 article_likes 重新计算所有文章的 like_count。
+// 点赞表启用前的 like_count 由客户端随意填写，启用时需要校正一次
+func RecountArticleLikes(db *gorm.DB) error {
+	return db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&model.Article{}).
+		UpdateColumn("like_count", gorm.Expr("(SELECT COUNT(*) FROM article_likes WHERE article_likes.article_id = articles.article_id)")).Error
+}
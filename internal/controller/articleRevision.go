@@ -0,0 +1,288 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+	"ar-backend/pkg/textdiff"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errArticleForbidden = errors.New("article forbidden")
+	errRevisionNotFound = errors.New("revision not found")
+)
+
+// lockArticle 在事务中锁定文章行，保证同一文章的版本号按顺序分配
+func lockArticle(tx *gorm.DB, articleID int, article *model.Article) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(article, articleID).Error
+}
+
+// saveArticleRevision 把文章当前内容保存为新版本，更新文章时需在同一事务中先调用 lockArticle
+func saveArticleRevision(tx *gorm.DB, article model.Article, editorID *int, restoredFrom *int) (model.ArticleRevision, error) {
+	var maxNo int
+	if err := tx.Model(&model.ArticleRevision{}).
+		Where("article_id = ?", article.ArticleID).
+		Select("COALESCE(MAX(revision_no), 0)").
+		Scan(&maxNo).Error; err != nil {
+		return model.ArticleRevision{}, err
+	}
+	revision := model.ArticleRevision{
+		ArticleID:    article.ArticleID,
+		RevisionNo:   maxNo + 1,
+		Title:        article.Title,
+		BodyText:     article.BodyText,
+		Category:     article.Category,
+		ImageFileID:  article.ImageFileID,
+		EditorID:     editorID,
+		RestoredFrom: restoredFrom,
+	}
+	return revision, tx.Create(&revision).Error
+}
+
+// createArticle 创建文章并保存为第 1 版
+func createArticle(db *gorm.DB, article *model.Article) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(article).Error; err != nil {
+			return err
+		}
+		_, err := saveArticleRevision(tx, *article, article.AuthorID, nil)
+		return err
+	})
+}
+
+// ensureBaseRevision 修订记录启用前创建的文章在第一次修改前补存原内容作为第 1 版
+func ensureBaseRevision(tx *gorm.DB, article model.Article) error {
+	var count int64
+	if err := tx.Model(&model.ArticleRevision{}).Where("article_id = ?", article.ArticleID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := saveArticleRevision(tx, article, nil, nil)
+	return err
+}
+
+// loadEditableArticle 读取文章并确认当前调用方是作者本人或编辑，失败时已写入响应
+func loadEditableArticle(c *gin.Context, db *gorm.DB) (model.Article, bool) {
+	articleID, _ := strconv.Atoi(c.Param("article_id"))
+	var article model.Article
+	if err := db.First(&article, articleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在", Code: 404})
+		return article, false
+	}
+	if !canEditArticle(c, article) {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "只能查看自己文章的修改记录", Code: 403})
+		return article, false
+	}
+	return article, true
+}
+
+// ListArticleRevisions godoc
+// @Summary 文章修改记录
+// @Description 分页获取文章的版本列表（不含正文），按版本号倒序。仅作者本人和编辑可查看
+// @Tags Articles
+// @Produce json
+// @Param article_id path int true "文章ID"
+// @Param page query int false "页码，默认 1"
+// @Param page_size query int false "每页条数，默认 20，最大 100"
+// @Success 200 {object} model.ListResponse[model.ArticleRevision]
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/articles/{article_id}/revisions [get]
+func ListArticleRevisions(c *gin.Context) {
+	db := database.GetDB()
+	article, ok := loadEditableArticle(c, db)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var revisions []model.ArticleRevision
+	var total int64
+	query := db.Model(&model.ArticleRevision{}).Where("article_id = ?", article.ArticleID)
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	if err := query.Omit("body_text").Order("revision_no DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.ListResponse[model.ArticleRevision]{Success: true, Total: total, List: revisions})
+}
+
+// GetArticleRevision godoc
+// @Summary 获取文章指定版本
+// @Description 获取文章某个版本的完整快照。仅作者本人和编辑可查看
+// @Tags Articles
+// @Produce json
+// @Param article_id path int true "文章ID"
+// @Param revision_no path int true "版本号"
+// @Success 200 {object} model.Response[model.ArticleRevision]
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/articles/{article_id}/revisions/{revision_no} [get]
+func GetArticleRevision(c *gin.Context) {
+	db := database.GetDB()
+	article, ok := loadEditableArticle(c, db)
+	if !ok {
+		return
+	}
+	revisionNo, _ := strconv.Atoi(c.Param("revision_no"))
+	var revision model.ArticleRevision
+	if err := db.Where("article_id = ? AND revision_no = ?", article.ArticleID, revisionNo).First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "版本不存在", Code: 404})
+		return
+	}
+	c.JSON(http.StatusOK, model.Response[model.ArticleRevision]{Success: true, Code: 200, Data: revision})
+}
+
+// DiffArticleRevisions godoc
+// @Summary 比较文章两个版本
+// @Description 返回两个版本之间标题是否变化及正文的逐行差异，to 省略时与最新版本比较。仅作者本人和编辑可查看
+// @Tags Articles
+// @Produce json
+// @Param article_id path int true "文章ID"
+// @Param from query int true "旧版本号"
+// @Param to query int false "新版本号，默认最新版本"
+// @Success 200 {object} model.Response[model.ArticleRevisionDiff]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/articles/{article_id}/revisions/diff [get]
+func DiffArticleRevisions(c *gin.Context) {
+	db := database.GetDB()
+	article, ok := loadEditableArticle(c, db)
+	if !ok {
+		return
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "缺少 from 参数", Code: 400})
+		return
+	}
+
+	var fromRev, toRev model.ArticleRevision
+	if err := db.Where("article_id = ? AND revision_no = ?", article.ArticleID, from).First(&fromRev).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "版本不存在", Code: 404})
+		return
+	}
+	toQuery := db.Where("article_id = ?", article.ArticleID)
+	if to := c.Query("to"); to != "" {
+		toNo, err := strconv.Atoi(to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "to 参数无效", Code: 400})
+			return
+		}
+		toQuery = toQuery.Where("revision_no = ?", toNo)
+	} else {
+		toQuery = toQuery.Order("revision_no DESC")
+	}
+	if err := toQuery.First(&toRev).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "版本不存在", Code: 404})
+		return
+	}
+
+	lines := textdiff.Lines(fromRev.BodyText, toRev.BodyText)
+	inserted, deleted := textdiff.Stats(lines)
+	c.JSON(http.StatusOK, model.Response[model.ArticleRevisionDiff]{Success: true, Code: 200, Data: model.ArticleRevisionDiff{
+		ArticleID:    article.ArticleID,
+		From:         fromRev.RevisionNo,
+		To:           toRev.RevisionNo,
+		TitleChanged: fromRev.Title != toRev.Title,
+		OldTitle:     fromRev.Title,
+		NewTitle:     toRev.Title,
+		Inserted:     inserted,
+		Deleted:      deleted,
+		Lines:        lines,
+	}})
+}
+
+// RestoreArticleRevision godoc
+// @Summary 恢复文章到指定版本
+// @Description 用指定版本的标题、正文、分类和图片覆盖文章当前内容，并保存为一个新版本，原有版本保持不变。仅作者本人和编辑可操作，作者恢复已发布或定时发布的文章后，文章转为待审核(in_review)
+// @Tags Articles
+// @Produce json
+// @Param article_id path int true "文章ID"
+// @Param revision_no path int true "要恢复的版本号"
+// @Success 200 {object} model.Response[model.Article]
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/articles/{article_id}/revisions/{revision_no}/restore [post]
+func RestoreArticleRevision(c *gin.Context) {
+	articleID, _ := strconv.Atoi(c.Param("article_id"))
+	revisionNo, _ := strconv.Atoi(c.Param("revision_no"))
+
+	db := database.GetDB()
+	var article model.Article
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockArticle(tx, articleID, &article); err != nil {
+			return err
+		}
+		if !canEditArticle(c, article) {
+			return errArticleForbidden
+		}
+		var revision model.ArticleRevision
+		if err := tx.Where("article_id = ? AND revision_no = ?", articleID, revisionNo).First(&revision).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errRevisionNotFound
+			}
+			return err
+		}
+		if err := tx.Model(&article).Updates(map[string]interface{}{
+			"title":         revision.Title,
+			"body_text":     revision.BodyText,
+			"category":      revision.Category,
+			"image_file_id": revision.ImageFileID,
+			"updated_at":    time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if _, err := resubmitEditedArticle(c, tx, article); err != nil {
+			return err
+		}
+		if err := tx.First(&article, articleID).Error; err != nil {
+			return err
+		}
+		_, err := saveArticleRevision(tx, article, optionalUserID(c), &revision.RevisionNo)
+		return err
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在", Code: 404})
+		return
+	case err == errRevisionNotFound:
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "版本不存在", Code: 404})
+		return
+	case err == errArticleForbidden:
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "只能修改自己的文章", Code: 403})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.Response[model.Article]{Success: true, Code: 200, Data: enrichArticle(c, db, article)})
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/middleware"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const articleSchedulerInterval = time.Minute // 定时发布、下线的检查间隔
+
+// articleTransitions 文章状态流转及所需权限：articles:write 表示作者本人即可，articles:manage 需要编辑或管理员
+var articleTransitions = map[string]map[string]auth.Permission{
+	model.ArticleDraft: {
+		model.ArticleInReview:  auth.PermArticlesWrite,
+		model.ArticleScheduled: auth.PermArticlesManage,
+		model.ArticlePublished: auth.PermArticlesManage,
+	},
+	model.ArticleInReview: {
+		model.ArticleDraft:     auth.PermArticlesWrite, // 作者撤回或编辑退回
+		model.ArticleScheduled: auth.PermArticlesManage,
+		model.ArticlePublished: auth.PermArticlesManage,
+	},
+	model.ArticleScheduled: {
+		model.ArticleDraft:     auth.PermArticlesManage,
+		model.ArticlePublished: auth.PermArticlesManage,
+	},
+	model.ArticlePublished: {
+		model.ArticleArchived: auth.PermArticlesWrite,
+	},
+	model.ArticleArchived: {
+		model.ArticleDraft:     auth.PermArticlesWrite,
+		model.ArticlePublished: auth.PermArticlesManage,
+	},
+}
+
+// canViewArticle 已发布的文章所有人可见，其余状态仅作者本人和编辑可见
+func canViewArticle(c *gin.Context, article model.Article) bool {
+	return article.Status == model.ArticlePublished || canEditArticle(c, article)
+}
+
+// visibleArticles 限定文章列表的可见范围：未指定状态时返回已发布的文章和自己的文章；
+// 指定非 published 状态时编辑可查看全部，其他人只能查看自己的
+func visibleArticles(c *gin.Context, query *gorm.DB, status string) *gorm.DB {
+	p := middleware.CurrentPrincipal(c)
+	userID := 0
+	if p != nil && !p.IsAPIKey() {
+		userID = p.UserID
+	}
+	if status == "" {
+		if userID == 0 {
+			return query.Where("status = ?", model.ArticlePublished)
+		}
+		return query.Where("status = ? OR author_id = ?", model.ArticlePublished, userID)
+	}
+	query = query.Where("status = ?", status)
+	if status != model.ArticlePublished && !p.Can(auth.PermArticlesManage) {
+		query = query.Where("author_id = ?", userID)
+	}
+	return query
+}
+
+// resubmitEditedArticle 作者修改已发布或待定时发布的文章后转为待审核，不能绕过审核直接改动线上内容；
+// 编辑和管理员的修改直接生效。article 为修改前加锁读取的文章，返回是否转为了待审核
+func resubmitEditedArticle(c *gin.Context, tx *gorm.DB, article model.Article) (bool, error) {
+	if article.Status != model.ArticlePublished && article.Status != model.ArticleScheduled {
+		return false, nil
+	}
+	if middleware.CurrentPrincipal(c).Can(auth.PermArticlesManage) {
+		return false, nil
+	}
+	err := tx.Model(&model.Article{}).Where("article_id = ?", article.ArticleID).
+		Updates(map[string]interface{}{"status": model.ArticleInReview, "updated_at": time.Now()}).Error
+	return err == nil, err
+}
+
+// ChangeArticleStatus godoc
+// @Summary 变更文章状态
+// @Description 文章按 draft → in_review → scheduled → published → archived 流转。作者可以提交审核、撤回、下线自己的文章和把已下线的文章改回草稿；定时发布、直接发布和重新上线需要编辑或管理员
+// @Tags Articles
+// @Accept json
+// @Produce json
+// @Param article_id path int true "文章ID"
+// @Param payload body model.ArticleStatusReqEdit true "目标状态"
+// @Success 200 {object} model.Response[model.Article]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 409 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/articles/{article_id}/status [put]
+func ChangeArticleStatus(c *gin.Context) {
+	articleID, _ := strconv.Atoi(c.Param("article_id"))
+	var req model.ArticleStatusReqEdit
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 400})
+		return
+	}
+
+	if _, ok := articleTransitions[req.Status]; !ok {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "无效的状态: " + req.Status, Code: 400})
+		return
+	}
+
+	db := database.GetDB()
+	var article model.Article
+	if err := db.First(&article, articleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在", Code: 404})
+		return
+	}
+	if !canEditArticle(c, article) {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "只能修改自己的文章", Code: 403})
+		return
+	}
+	perm, ok := articleTransitions[article.Status][req.Status]
+	if !ok {
+		c.JSON(http.StatusConflict, model.BaseResponse{Success: false, ErrMessage: fmt.Sprintf("文章不能从 %s 变更为 %s", article.Status, req.Status), Code: 409})
+		return
+	}
+	if !middleware.CurrentPrincipal(c).Can(perm) {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "需要编辑审核", Code: 403})
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": req.Status, "updated_at": now}
+	switch req.Status {
+	case model.ArticleScheduled:
+		if req.PublishAt == nil || !req.PublishAt.After(now) {
+			c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "定时发布需要晚于当前时间的 publish_at", Code: 400})
+			return
+		}
+		updates["published_at"] = *req.PublishAt
+	case model.ArticlePublished:
+		updates["published_at"] = now
+	}
+	if req.Status == model.ArticleScheduled || req.Status == model.ArticlePublished {
+		// 未指定 unpublish_at 时清除旧的下线时间，避免重新上线后立即被下线
+		if req.UnpublishAt != nil {
+			start := now
+			if req.PublishAt != nil && req.Status == model.ArticleScheduled {
+				start = *req.PublishAt
+			}
+			if !req.UnpublishAt.After(start) {
+				c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "unpublish_at 必须晚于发布时间", Code: 400})
+				return
+			}
+		}
+		updates["unpublish_at"] = req.UnpublishAt
+	}
+
+	// 以当前状态为条件更新，避免与定时任务或其他请求并发修改
+	result := db.Model(&model.Article{}).
+		Where("article_id = ? AND status = ?", article.ArticleID, article.Status).
+		Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: result.Error.Error(), Code: 500})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, model.BaseResponse{Success: false, ErrMessage: "文章状态已变化，请刷新后重试", Code: 409})
+		return
+	}
+	if err := db.First(&article, article.ArticleID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.Response[model.Article]{Success: true, Code: 200, Data: enrichArticle(c, db, article)})
+}
+
+// PublishScheduledArticles 发布 published_at 已到的定时文章，下线 unpublish_at 已到的文章，返回各自的条数
+func PublishScheduledArticles(db *gorm.DB, now time.Time) (published int64, archived int64, err error) {
+	result := db.Model(&model.Article{}).
+		Where("status = ? AND published_at <= ?", model.ArticleScheduled, now).
+		Updates(map[string]interface{}{"status": model.ArticlePublished, "updated_at": now})
+	if result.Error != nil {
+		return 0, 0, result.Error
+	}
+	published = result.RowsAffected
+
+	result = db.Model(&model.Article{}).
+		Where("status = ? AND unpublish_at <= ?", model.ArticlePublished, now).
+		Updates(map[string]interface{}{"status": model.ArticleArchived, "updated_at": now})
+	return published, result.RowsAffected, result.Error
+}
+
+// StartArticleScheduler 启动后台任务，每分钟处理一次定时发布和到期下线。
+// 更新以状态为条件，多实例同时运行也不会重复处理
+func StartArticleScheduler(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(articleSchedulerInterval)
+		defer ticker.Stop()
+		for {
+			if published, archived, err := PublishScheduledArticles(db, time.Now()); err != nil {
+				log.Printf("文章定时发布失败: %v", err)
+			} else if published > 0 || archived > 0 {
+				log.Printf("定时发布 %d 篇文章，下线 %d 篇文章", published, archived)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"ar-backend/internal/auth"
+	"ar-backend/internal/middleware"
 	"ar-backend/internal/model"
 	"ar-backend/pkg/aws"
 	"ar-backend/pkg/database"
@@ -9,24 +11,19 @@ import (
 	"path/filepath"
 	"strconv"
 
-	"strings"
-
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"gorm.io/gorm"
 )
 
 // CreateArticleWithImage godoc
 // @Summary 新建文章（支持图片上传）
-// @Description 新建一个文章并同时上传图片到S3
+// @Description 新建一个文章并同时上传图片到S3，新文章为草稿，需提交审核后发布
 // @Tags Articles
 // @Accept multipart/form-data
 // @Produce json
 // @Param title formData string true "文章标题"
 // @Param body_text formData string true "文章内容"
 // @Param category formData string false "文章分类"
-// @Param like_count formData int false "点赞数"
-// @Param comment_count formData int false "评论数"
 // @Param image formData file false "文章图片"
 // @Success 200 {object} model.Response[model.Article]
 // @Failure 400 {object} model.BaseResponse
@@ -35,8 +32,7 @@ import (
 // @Security ApiKeyAuth
 // @Router /api/articles/with-image [post]
 func CreateArticleWithImage(c *gin.Context) {
-	// 1. 获取并校验access token（可选，根据需要）
-	// 暂时跳过token验证，方便测试
+	// 1. access token 已由 JWTAuth 中间件校验
 	
 	// 2. 解析表单数据
 	var req model.ArticleCreateWithImageRequest
@@ -96,6 +92,7 @@ func CreateArticleWithImage(c *gin.Context) {
 			S3URL:     s3URL,
 			Location:  "article-images", // 文章图片的位置标识
 			RelatedID: 0, // 暂时设为0，创建文章后会更新
+			UserID:    optionalUserID(c),
 		}
 
 		db := database.GetDB()
@@ -114,13 +111,13 @@ func CreateArticleWithImage(c *gin.Context) {
 		Title:        req.Title,
 		BodyText:     req.BodyText,
 		Category:     req.Category,
-		LikeCount:    req.LikeCount,
 		ImageFileID:  imageFileID,
-		CommentCount: req.CommentCount,
+		AuthorID:     optionalUserID(c),
+		Status:       model.ArticleDraft,
 	}
 
 	db := database.GetDB()
-	if err := db.Create(&article).Error; err != nil {
+	if err := createArticle(db, &article); err != nil {
 		// 如果文章创建失败且已上传图片，删除图片记录和S3文件
 		if imageFileID != nil {
 			var fileRecord model.File
@@ -141,14 +138,14 @@ func CreateArticleWithImage(c *gin.Context) {
 	}
 
 	// 6. 获取完整的文章信息（包含图片URL）
-	enrichedArticle := enrichArticleWithImageURL(db, article)
+	enrichedArticle := enrichArticle(c, db, article)
 
 	c.JSON(http.StatusOK, model.Response[model.Article]{Success: true, Data: enrichedArticle})
 }
 
 // CreateArticle godoc
 // @Summary 新建文章
-// @Description 新建一个文章
+// @Description 新建一个文章，新文章为草稿，需提交审核后发布
 // @Tags Articles
 // @Accept json
 // @Produce json
@@ -160,25 +157,7 @@ func CreateArticleWithImage(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Router /api/articles [post]
 func CreateArticle(c *gin.Context) {
-	// 1. 获取并校验access token
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(401, model.BaseResponse{Success: false, ErrMessage: "未登录，缺少token"})
-		return
-	}
-	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-	type UserIDClaims struct {
-		UserID int `json:"user_id"`
-		jwt.RegisteredClaims
-	}
-	claims := &UserIDClaims{}
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return getJWTSecret(), nil
-	})
-	if err != nil || !token.Valid {
-		c.JSON(401, model.BaseResponse{Success: false, ErrMessage: "token无效或已过期"})
-		return
-	}
+	// 1. access token 已由 JWTAuth 中间件校验
 	// 2. 解析请求体
 	var req model.ArticleReqCreate
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -190,26 +169,25 @@ func CreateArticle(c *gin.Context) {
 		Title:        req.Title,
 		BodyText:     req.BodyText,
 		Category:     req.Category,
-		LikeCount:    req.LikeCount,
 		ArticleImage: req.ArticleImage,
 		ImageFileID:  req.ImageFileID,
-		CommentCount: req.CommentCount,
-		// 可选：UserID: claims.UserID,
+		AuthorID:     optionalUserID(c),
+		Status:       model.ArticleDraft,
 	}
 	db := database.GetDB()
-	if err := db.Create(&article).Error; err != nil {
+	if err := createArticle(db, &article); err != nil {
 		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
 		return
 	}
 
 	// 获取完整的文章信息（包含图片URL）
-	enrichedArticle := enrichArticleWithImageURL(db, article)
+	enrichedArticle := enrichArticle(c, db, article)
 	c.JSON(http.StatusOK, model.Response[model.Article]{Success: true, Data: enrichedArticle})
 }
 
 // UpdateArticle godoc
 // @Summary 更新文章
-// @Description 更新文章信息
+// @Description 更新文章信息，仅作者本人或编辑、管理员可以修改。每次修改都会保存一个新版本。作者修改已发布或定时发布的文章后，文章转为待审核(in_review)，需编辑重新发布
 // @Tags Articles
 // @Accept json
 // @Produce json
@@ -217,6 +195,7 @@ func CreateArticle(c *gin.Context) {
 // @Success 200 {object} model.BaseResponse
 // @Failure 400 {object} model.BaseResponse
 // @Failure 401 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
 // @Failure 500 {object} model.BaseResponse
 // @Security ApiKeyAuth
 // @Router /api/articles [put]
@@ -227,18 +206,54 @@ func UpdateArticle(c *gin.Context) {
 		return
 	}
 	db := database.GetDB()
-	var article model.Article
-	if err := db.First(&article, req.ArticleID).Error; err != nil {
+	resubmitted := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var article model.Article
+		if err := lockArticle(tx, req.ArticleID, &article); err != nil {
+			return err
+		}
+		if !canEditArticle(c, article) {
+			return errArticleForbidden
+		}
+		if err := ensureBaseRevision(tx, article); err != nil {
+			return err
+		}
+		if err := tx.Model(&article).Updates(req).Error; err != nil {
+			return err
+		}
+		moved, err := resubmitEditedArticle(c, tx, article)
+		if err != nil {
+			return err
+		}
+		resubmitted = moved
+		// 每次修改都保存一份完整快照，可通过修改记录接口比较和恢复
+		if err := tx.First(&article, article.ArticleID).Error; err != nil {
+			return err
+		}
+		_, err = saveArticleRevision(tx, article, optionalUserID(c), nil)
+		return err
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound:
 		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在"})
 		return
+	case err == errArticleForbidden:
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "只能修改自己的文章"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
+		return
+	}
+	if resubmitted {
+		c.JSON(http.StatusOK, model.BaseResponse{Success: true, ErrMessage: "修改已保存，文章已转为待审核，审核通过后重新发布"})
+		return
 	}
-	db.Model(&article).Updates(req)
 	c.JSON(http.StatusOK, model.BaseResponse{Success: true})
 }
 
 // DeleteArticle godoc
 // @Summary 删除文章
-// @Description 删除一个文章
+// @Description 删除一个文章及其修改记录、点赞和评论，仅作者本人或编辑、管理员可以删除
 // @Tags Articles
 // @Accept json
 // @Produce json
@@ -246,6 +261,7 @@ func UpdateArticle(c *gin.Context) {
 // @Success 200 {object} model.BaseResponse
 // @Failure 400 {object} model.BaseResponse
 // @Failure 401 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
 // @Failure 500 {object} model.BaseResponse
 // @Security ApiKeyAuth
 // @Router /api/articles/{article_id} [delete]
@@ -259,6 +275,10 @@ func DeleteArticle(c *gin.Context) {
 		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在"})
 		return
 	}
+	if !canEditArticle(c, article) {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "只能删除自己的文章"})
+		return
+	}
 
 	// 如果文章有关联的图片文件，先删除
 	if article.ImageFileID != nil {
@@ -275,7 +295,19 @@ func DeleteArticle(c *gin.Context) {
 		}
 	}
 
-	if err := db.Delete(&model.Article{}, articleID).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("article_id = ?", articleID).Delete(&model.ArticleRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id = ?", articleID).Delete(&model.ArticleLike{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("article_id = ?", articleID).Delete(&model.Comment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Article{}, articleID).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
 		return
 	}
@@ -284,7 +316,7 @@ func DeleteArticle(c *gin.Context) {
 
 // GetArticle godoc
 // @Summary 获取文章信息
-// @Description 获取单个文章信息
+// @Description 获取单个文章信息，未发布的文章仅作者本人和编辑可见
 // @Tags Articles
 // @Accept json
 // @Produce json
@@ -298,19 +330,19 @@ func GetArticle(c *gin.Context) {
 	articleID, _ := strconv.Atoi(id)
 	db := database.GetDB()
 	var article model.Article
-	if err := db.First(&article, articleID).Error; err != nil {
+	if err := db.First(&article, articleID).Error; err != nil || !canViewArticle(c, article) {
 		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在"})
 		return
 	}
 
 	// 获取完整的文章信息（包含图片URL）
-	enrichedArticle := enrichArticleWithImageURL(db, article)
+	enrichedArticle := enrichArticle(c, db, article)
 	c.JSON(http.StatusOK, model.Response[model.Article]{Success: true, Data: enrichedArticle})
 }
 
 // ListArticles godoc
 // @Summary 获取文章列表
-// @Description 获取文章分页列表
+// @Description 获取文章分页列表，可按关键字、作者和状态筛选。未登录只返回已发布的文章，登录后同时返回自己的草稿等文章
 // @Tags Articles
 // @Accept json
 // @Produce json
@@ -328,19 +360,19 @@ func ListArticles(c *gin.Context) {
 	var articles []model.Article
 	var total int64
 
-	query := db.Model(&model.Article{})
+	query := visibleArticles(c, db.Model(&model.Article{}), req.Status)
 	if req.Keyword != "" {
 		query = query.Where("title LIKE ? OR body_text LIKE ?", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
 	}
+	if req.AuthorID != 0 {
+		query = query.Where("author_id = ?", req.AuthorID)
+	}
 
 	query.Count(&total)
 	query.Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&articles)
 
-	// 为每篇文章添加图片URL
-	enrichedArticles := make([]model.Article, len(articles))
-	for i, article := range articles {
-		enrichedArticles[i] = enrichArticleWithImageURL(db, article)
-	}
+	// 为文章批量添加图片URL、作者信息和点赞标记
+	enrichedArticles := enrichArticles(c, db, articles)
 
 	c.JSON(http.StatusOK, model.ListResponse[model.Article]{
 		Success: true,
@@ -349,17 +381,77 @@ func ListArticles(c *gin.Context) {
 	})
 }
 
-// 辅助函数：为文章添加图片URL
-func enrichArticleWithImageURL(db *gorm.DB, article model.Article) model.Article {
-	if article.ImageFileID != nil {
-		var fileRecord model.File
-		if db.First(&fileRecord, *article.ImageFileID).Error == nil {
-			if fileRecord.S3URL != "" {
-				article.ImageURL = fileRecord.S3URL
-			}
+// 辅助函数：为文章添加图片URL、作者信息，携带 token 时标记当前用户是否已点赞
+func enrichArticle(c *gin.Context, db *gorm.DB, article model.Article) model.Article {
+	return enrichArticles(c, db, []model.Article{article})[0]
+}
+
+// enrichArticles 批量为文章添加图片URL、作者信息和点赞标记，每项信息只查询一次，与文章篇数无关
+func enrichArticles(c *gin.Context, db *gorm.DB, articles []model.Article) []model.Article {
+	if len(articles) == 0 {
+		return []model.Article{}
+	}
+	var fileIDs, authorIDs, articleIDs []int
+	for _, article := range articles {
+		articleIDs = append(articleIDs, article.ArticleID)
+		if article.ImageFileID != nil {
+			fileIDs = append(fileIDs, *article.ImageFileID)
+		}
+		if article.AuthorID != nil {
+			authorIDs = append(authorIDs, *article.AuthorID)
+		}
+	}
+
+	imageURLs := map[int]string{}
+	if len(fileIDs) > 0 {
+		var files []model.File
+		db.Select("file_id", "s3_url").Where("file_id IN ?", fileIDs).Find(&files)
+		for _, f := range files {
+			imageURLs[f.FileID] = f.S3URL
 		}
 	}
-	return article
+	authors := map[int]*model.AuthorSummary{}
+	if len(authorIDs) > 0 {
+		var users []model.User
+		db.Select("user_id", "name", "avatar").Where("user_id IN ?", authorIDs).Find(&users)
+		for _, u := range users {
+			authors[u.UserID] = &model.AuthorSummary{UserID: u.UserID, Name: u.Name, Avatar: u.Avatar}
+		}
+	}
+	userID := c.GetInt("user_id")
+	liked := map[int]bool{}
+	if userID != 0 {
+		var likedIDs []int
+		db.Model(&model.ArticleLike{}).Where("article_id IN ? AND user_id = ?", articleIDs, userID).Pluck("article_id", &likedIDs)
+		for _, id := range likedIDs {
+			liked[id] = true
+		}
+	}
+
+	enriched := make([]model.Article, len(articles))
+	for i, article := range articles {
+		if article.ImageFileID != nil && imageURLs[*article.ImageFileID] != "" {
+			article.ImageURL = imageURLs[*article.ImageFileID]
+		}
+		if article.AuthorID != nil {
+			article.Author = authors[*article.AuthorID]
+		}
+		if userID != 0 {
+			likedByMe := liked[article.ArticleID]
+			article.LikedByMe = &likedByMe
+		}
+		enriched[i] = article
+	}
+	return enriched
+}
+
+// canEditArticle 作者本人或拥有 articles:manage 权限的调用方可以修改、删除文章
+func canEditArticle(c *gin.Context, article model.Article) bool {
+	p := middleware.CurrentPrincipal(c)
+	if p.Can(auth.PermArticlesManage) {
+		return true
+	}
+	return p != nil && !p.IsAPIKey() && article.AuthorID != nil && *article.AuthorID == p.UserID
 }
 
 // 辅助函数：根据文件扩展名获取 MIME 类型（文章图片专用）
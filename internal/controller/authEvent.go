@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const defaultAuthEventRetentionDays = 180 // 认证事件默认保留天数
+
+// recordAuthEvent 写入一条认证事件。userID 为 0 表示无法对应到用户；写入失败只记日志，不影响请求
+func recordAuthEvent(c *gin.Context, db *gorm.DB, userID int, email string, eventType string, success bool, detail string) {
+	meta := newSessionMeta(c)
+	event := model.AuthEvent{
+		Email:     truncate(strings.ToLower(strings.TrimSpace(email)), 320),
+		EventType: eventType,
+		Success:   success,
+		Detail:    truncate(detail, 255),
+		IPAddress: meta.IPAddress,
+		UserAgent: meta.UserAgent,
+		Platform:  meta.Platform,
+	}
+	if userID != 0 {
+		event.UserID = &userID
+	}
+	if err := db.Create(&event).Error; err != nil {
+		log.Printf("记录认证事件失败 (%s): %v", eventType, err)
+	}
+}
+
+// ListMyAuthEvents godoc
+// @Summary 我的登录与安全记录
+// @Description 分页获取当前用户的认证事件（登录、token 刷新、第三方关联、密码和两步验证变更等），按时间倒序
+// @Tags Auth
+// @Produce json
+// @Param page query int false "页码，默认 1"
+// @Param page_size query int false "每页条数，默认 20，最大 100"
+// @Success 200 {object} model.ListResponse[model.AuthEvent]
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/events [get]
+func ListMyAuthEvents(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	db := database.GetDB()
+	var events []model.AuthEvent
+	var total int64
+	query := db.Model(&model.AuthEvent{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	if err := query.Order("created_at DESC, event_id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.ListResponse[model.AuthEvent]{Success: true, Total: total, List: events})
+}
+
+// ListAuthEvents godoc
+// @Summary 查询认证事件
+// @Description 管理员按用户、邮箱、事件类型、结果、IP 和时间范围分页查询认证事件，按时间倒序
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param req body model.AuthEventReqList true "查询条件"
+// @Success 200 {object} model.ListResponse[model.AuthEvent]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth-events/list [post]
+func ListAuthEvents(c *gin.Context) {
+	var req model.AuthEventReqList
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 400})
+		return
+	}
+
+	db := database.GetDB()
+	query := db.Model(&model.AuthEvent{})
+	if req.UserID != 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.Email != "" {
+		query = query.Where("email = ?", strings.ToLower(strings.TrimSpace(req.Email)))
+	}
+	if req.EventType != "" {
+		query = query.Where("event_type = ?", req.EventType)
+	}
+	if req.Success != nil {
+		query = query.Where("success = ?", *req.Success)
+	}
+	if req.IPAddress != "" {
+		query = query.Where("ip_address = ?", req.IPAddress)
+	}
+	if req.From != nil {
+		query = query.Where("created_at >= ?", *req.From)
+	}
+	if req.To != nil {
+		query = query.Where("created_at < ?", *req.To)
+	}
+
+	var events []model.AuthEvent
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	if err := query.Order("created_at DESC, event_id DESC").Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&events).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.ListResponse[model.AuthEvent]{Success: true, Total: total, List: events})
+}
+
+// authEventRetention 认证事件保留时长，由 AUTH_EVENT_RETENTION_DAYS 配置
+func authEventRetention() time.Duration {
+	days := defaultAuthEventRetentionDays
+	if v, err := strconv.Atoi(os.Getenv("AUTH_EVENT_RETENTION_DAYS")); err == nil && v > 0 {
+		days = v
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PurgeAuthEvents 删除超过保留期的认证事件，返回删除条数
+func PurgeAuthEvents(db *gorm.DB, now time.Time) (int64, error) {
+	result := db.Where("created_at < ?", now.Add(-authEventRetention())).Delete(&model.AuthEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// StartAuthEventRetention 启动后台任务，启动时及之后每天清理一次过期的认证事件
+func StartAuthEventRetention(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for {
+			if n, err := PurgeAuthEvents(db, time.Now()); err != nil {
+				log.Printf("清理过期认证事件失败: %v", err)
+			} else if n > 0 {
+				log.Printf("已清理 %d 条过期认证事件", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	commentDefaultDepth   = 2  // 默认展开的回复层数
+	commentMaxDepth       = 5  // 一次请求最多展开的回复层数
+	commentDefaultReplies = 3  // 每条评论默认返回的回复数
+	commentMaxReplies     = 20 // 每条评论最多返回的回复数
+)
+
+// queryIntInRange 读取整数查询参数，缺省或超出范围时使用默认值
+func queryIntInRange(c *gin.Context, key string, def, min, max int) int {
+	v, err := strconv.Atoi(c.Query(key))
+	if err != nil || v < min || v > max {
+		return def
+	}
+	return v
+}
+
+// ListArticleComments godoc
+// @Summary 获取文章评论
+// @Description 分页获取文章已发布的评论树。不传 parent_id 时按时间倒序分页返回顶层评论；传 parent_id 时按时间顺序分页返回该评论的直接回复，用于展开更多回复。
+// @Description 每条评论附带 reply_count，并按 depth 展开若干层、每层最多 replies 条回复，has_more_replies 表示还有未返回的回复
+// @Tags Comments
+// @Produce json
+// @Param article_id path int true "文章ID"
+// @Param parent_id query int false "父评论ID，省略时返回顶层评论"
+// @Param page query int false "页码，默认 1"
+// @Param page_size query int false "每页条数，默认 20，最大 100"
+// @Param depth query int false "展开的回复层数，默认 2，最大 5，0 表示不展开"
+// @Param replies query int false "每条评论展开的回复数，默认 3，最大 20"
+// @Success 200 {object} model.ListResponse[model.CommentThread]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/articles/{article_id}/comments [get]
+func ListArticleComments(c *gin.Context) {
+	articleID, _ := strconv.Atoi(c.Param("article_id"))
+	db := database.GetDB()
+	var article model.Article
+	if err := db.First(&article, articleID).Error; err != nil || !canViewArticle(c, article) {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在", Code: 404})
+		return
+	}
+	page := queryIntInRange(c, "page", 1, 1, math.MaxInt32)
+	pageSize := queryIntInRange(c, "page_size", 20, 1, 100)
+	depth := queryIntInRange(c, "depth", commentDefaultDepth, 0, commentMaxDepth)
+	replyLimit := queryIntInRange(c, "replies", commentDefaultReplies, 1, commentMaxReplies)
+
+	query := db.Model(&model.Comment{}).Where("article_id = ? AND is_published = ?", articleID, true)
+	order := "created_at DESC, comment_id DESC"
+	if parent := c.Query("parent_id"); parent != "" {
+		parentID, err := strconv.Atoi(parent)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "parent_id 参数无效", Code: 400})
+			return
+		}
+		query = query.Where("reply_to_comment_id = ?", parentID)
+		order = "created_at, comment_id"
+	} else {
+		query = query.Where("reply_to_comment_id IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	var comments []model.Comment
+	if err := query.Order(order).Offset((page - 1) * pageSize).Limit(pageSize).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	threads, err := buildCommentThreads(db, comments, depth, replyLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.ListResponse[model.CommentThread]{Success: true, Total: total, List: threads})
+}
+
+// buildCommentThreads 为评论附加回复数并逐层展开回复，每层只查询两次，与评论条数无关
+func buildCommentThreads(db *gorm.DB, comments []model.Comment, depth int, replyLimit int) ([]model.CommentThread, error) {
+	threads := make([]model.CommentThread, len(comments))
+	if len(comments) == 0 {
+		return threads, nil
+	}
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.CommentID
+		threads[i] = model.CommentThread{Comment: comment, Replies: []model.CommentThread{}}
+	}
+
+	var counts []struct {
+		ParentID int
+		Count    int64
+	}
+	if err := db.Model(&model.Comment{}).
+		Select("reply_to_comment_id AS parent_id, COUNT(*) AS count").
+		Where("reply_to_comment_id IN ? AND is_published = ?", ids, true).
+		Group("reply_to_comment_id").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	index := make(map[int]int, len(threads))
+	for i, t := range threads {
+		index[t.CommentID] = i
+	}
+	for _, row := range counts {
+		threads[index[row.ParentID]].ReplyCount = row.Count
+	}
+
+	if depth > 0 {
+		// 每条评论只取按时间顺序的前 replyLimit 条回复
+		ranked := db.Model(&model.Comment{}).
+			Select("*, ROW_NUMBER() OVER (PARTITION BY reply_to_comment_id ORDER BY created_at, comment_id) AS rn").
+			Where("reply_to_comment_id IN ? AND is_published = ?", ids, true)
+		var replies []model.Comment
+		if err := db.Table("(?) AS ranked", ranked).Where("rn <= ?", replyLimit).
+			Order("created_at, comment_id").Find(&replies).Error; err != nil {
+			return nil, err
+		}
+		children, err := buildCommentThreads(db, replies, depth-1, replyLimit)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			i := index[*child.ReplyToCommentID]
+			threads[i].Replies = append(threads[i].Replies, child)
+		}
+	}
+	for i := range threads {
+		threads[i].HasMoreReplies = threads[i].ReplyCount > int64(len(threads[i].Replies))
+	}
+	return threads, nil
+}
+
+// commentSubtreeIDs 返回评论本身及其全部下级回复的ID
+func commentSubtreeIDs(tx *gorm.DB, commentID int) ([]int, error) {
+	var ids []int
+	// UNION 去重，即使回复关系被改成环也能结束
+	err := tx.Raw(`WITH RECURSIVE subtree AS (
+		SELECT comment_id FROM comments WHERE comment_id = ?
+		UNION
+		SELECT c.comment_id FROM comments c JOIN subtree s ON c.reply_to_comment_id = s.comment_id
+	) SELECT comment_id FROM subtree`, commentID).Scan(&ids).Error
+	return ids, err
+}
+
+// recountArticleComments 按已发布的评论重新计算文章的 comment_count，需在同一事务中先调用 lockArticle
+func recountArticleComments(tx *gorm.DB, articleID int) error {
+	return tx.Model(&model.Article{}).Where("article_id = ?", articleID).
+		UpdateColumn("comment_count", gorm.Expr("(SELECT COUNT(*) FROM comments WHERE comments.article_id = articles.article_id AND comments.is_published)")).Error
+}
+
+// RecountArticleComments 按已发布的评论重新计算所有文章的 comment_count。
+// 评论数改由评论接口维护之前由客户端随意填写，启用时需要校正一次
+func RecountArticleComments(db *gorm.DB) error {
+	return db.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&model.Article{}).
+		UpdateColumn("comment_count", gorm.Expr("(SELECT COUNT(*) FROM comments WHERE comments.article_id = articles.article_id AND comments.is_published)")).Error
+}
@@ -1,24 +1,69 @@
 package controller
 
 import (
+	"ar-backend/internal/auth"
+	"ar-backend/internal/middleware"
 	"ar-backend/internal/model"
 	"ar-backend/pkg/database"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+var errInvalidReplyTarget = errors.New("invalid reply target")
+
+// canEditComment 评论作者本人或拥有 comments:manage 权限的调用方可以修改、删除评论
+func canEditComment(c *gin.Context, comment model.Comment) bool {
+	p := middleware.CurrentPrincipal(c)
+	if p.Can(auth.PermCommentsManage) {
+		return true
+	}
+	return p != nil && !p.IsAPIKey() && comment.UserID == p.UserID
+}
+
+// checkReplyTarget 确认被回复的评论属于同一篇文章，且不是 commentID 自身或其下级回复（commentID 为 0 表示新评论）
+func checkReplyTarget(tx *gorm.DB, articleID int, replyTo *int, commentID int) error {
+	if replyTo == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&model.Comment{}).Where("comment_id = ? AND article_id = ?", *replyTo, articleID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errInvalidReplyTarget
+	}
+	if commentID == 0 {
+		return nil
+	}
+	subtree, err := commentSubtreeIDs(tx, commentID)
+	if err != nil {
+		return err
+	}
+	for _, id := range subtree {
+		if id == *replyTo {
+			return errInvalidReplyTarget
+		}
+	}
+	return nil
+}
+
 // CreateComment godoc
 // @Summary 新建评论
-// @Description 新建一条评论
+// @Description 以当前登录用户的身份新建一条评论或回复，同时更新文章的评论数。只能评论自己可见的文章，被回复的评论须属于同一篇文章
 // @Tags Comments
 // @Accept json
 // @Produce json
 // @Param comment body model.CommentReqCreate true "评论信息"
 // @Success 200 {object} model.Response[model.Comment]
 // @Failure 400 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
 // @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
 // @Router /api/comments [post]
 func CreateComment(c *gin.Context) {
 	var req model.CommentReqCreate
@@ -26,16 +71,44 @@ func CreateComment(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: err.Error()})
 		return
 	}
+	userID := c.GetInt("user_id")
+	if userID == 0 {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "评论需要以用户身份登录"})
+		return
+	}
 
 	comment := model.Comment{
 		ArticleID:        req.ArticleID,
-		UserID:           req.UserID,
+		UserID:           userID,
 		CommentText:      req.CommentText,
 		IsPublished:      req.IsPublished,
 		ReplyToCommentID: req.ReplyToCommentID,
 	}
 	db := database.GetDB()
-	if err := db.Create(&comment).Error; err != nil {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var article model.Article
+		if err := lockArticle(tx, req.ArticleID, &article); err != nil {
+			return err
+		}
+		if !canViewArticle(c, article) {
+			return errArticleNotVisible
+		}
+		if err := checkReplyTarget(tx, req.ArticleID, req.ReplyToCommentID, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		return recountArticleComments(tx, req.ArticleID)
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound || err == errArticleNotVisible:
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在"})
+		return
+	case err == errInvalidReplyTarget:
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "被回复的评论不存在"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
 		return
 	}
@@ -44,14 +117,17 @@ func CreateComment(c *gin.Context) {
 
 // UpdateComment godoc
 // @Summary 更新评论
-// @Description 更新评论内容
+// @Description 更新评论内容，仅评论作者本人或编辑、管理员可以修改
 // @Tags Comments
 // @Accept json
 // @Produce json
 // @Param comment body model.CommentReqEdit true "评论信息"
 // @Success 200 {object} model.BaseResponse
 // @Failure 400 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
 // @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
 // @Router /api/comments [put]
 func UpdateComment(c *gin.Context) {
 	var req model.CommentReqEdit
@@ -65,26 +141,81 @@ func UpdateComment(c *gin.Context) {
 		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "评论不存在"})
 		return
 	}
-	db.Model(&comment).Updates(req)
+	if !canEditComment(c, comment) {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "只能修改自己的评论"})
+		return
+	}
+	// 发布状态可能变化，在同一事务中重新计算文章的评论数
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var article model.Article
+		if err := lockArticle(tx, comment.ArticleID, &article); err != nil {
+			return err
+		}
+		if err := checkReplyTarget(tx, comment.ArticleID, req.ReplyToCommentID, comment.CommentID); err != nil {
+			return err
+		}
+		if err := tx.Model(&comment).Updates(req).Error; err != nil {
+			return err
+		}
+		return recountArticleComments(tx, comment.ArticleID)
+	})
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在"})
+		return
+	case err == errInvalidReplyTarget:
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "被回复的评论不存在或是该评论的下级回复"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, model.BaseResponse{Success: true})
 }
 
 // DeleteComment godoc
 // @Summary 删除评论
-// @Description 删除一条评论
+// @Description 删除一条评论及其全部回复，同时更新文章的评论数。仅评论作者本人或编辑、管理员可以删除
 // @Tags Comments
 // @Accept json
 // @Produce json
 // @Param comment_id path int true "评论ID"
 // @Success 200 {object} model.BaseResponse
 // @Failure 400 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
 // @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
 // @Router /api/comments/{comment_id} [delete]
 func DeleteComment(c *gin.Context) {
 	id := c.Param("comment_id")
 	commentID, _ := strconv.Atoi(id)
 	db := database.GetDB()
-	if err := db.Delete(&model.Comment{}, commentID).Error; err != nil {
+	var comment model.Comment
+	if err := db.First(&comment, commentID).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "评论不存在"})
+		return
+	}
+	if !canEditComment(c, comment) {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "只能删除自己的评论"})
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 文章已不存在时仍允许删除遗留的评论
+		var article model.Article
+		if err := lockArticle(tx, comment.ArticleID, &article); err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		ids, err := commentSubtreeIDs(tx, comment.CommentID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.Comment{}, ids).Error; err != nil {
+			return err
+		}
+		return recountArticleComments(tx, comment.ArticleID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
 		return
 	}
@@ -93,7 +224,7 @@ func DeleteComment(c *gin.Context) {
 
 // GetComment godoc
 // @Summary 获取单个评论
-// @Description 获取一条评论信息
+// @Description 获取一条已发布的评论，所属文章须对当前调用方可见；未发布的评论仅作者本人和编辑可见
 // @Tags Comments
 // @Accept json
 // @Produce json
@@ -111,18 +242,26 @@ func GetComment(c *gin.Context) {
 		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "评论不存在"})
 		return
 	}
+	var article model.Article
+	if err := db.First(&article, comment.ArticleID).Error; err != nil || !canViewArticle(c, article) ||
+		(!comment.IsPublished && !canEditComment(c, comment)) {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "评论不存在"})
+		return
+	}
 	c.JSON(http.StatusOK, model.Response[model.Comment]{Success: true, Data: comment})
 }
 
 // ListComments godoc
 // @Summary 获取评论列表
-// @Description 获取评论分页列表
+// @Description 按时间倒序分页获取文章已发布的评论（不分层级），文章须对当前调用方可见。需要评论树时使用 /api/articles/{article_id}/comments
 // @Tags Comments
 // @Accept json
 // @Produce json
 // @Param req body model.CommentReqList true "分页与搜索"
 // @Success 200 {object} model.ListResponse[model.Comment]
 // @Failure 400 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
 // @Router /api/comments/list [post]
 func ListComments(c *gin.Context) {
 	var req model.CommentReqList
@@ -130,12 +269,32 @@ func ListComments(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: err.Error()})
 		return
 	}
+	if req.Page < 1 || req.PageSize < 1 || req.PageSize > 100 {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "page 须大于 0，page_size 须在 1 到 100 之间"})
+		return
+	}
 	db := database.GetDB()
-	var comments []model.Comment
-	var total int64
+	var article model.Article
+	if err := db.First(&article, req.ArticleID).Error; err != nil || !canViewArticle(c, article) {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "文章不存在"})
+		return
+	}
 
-	db.Model(&model.Comment{}).Count(&total)
-	db.Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&comments)
+	query := db.Model(&model.Comment{}).Where("article_id = ? AND is_published = ?", req.ArticleID, true)
+	if req.Keyword != "" {
+		query = query.Where("comment_text LIKE ?", "%"+req.Keyword+"%")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
+		return
+	}
+	var comments []model.Comment
+	if err := query.Order("created_at DESC, comment_id DESC").
+		Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, model.ListResponse[model.Comment]{
 		Success: true,
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondAuth 返回登录结果。请求携带 "X-Session-Mode: cookie" 时 token 写入 HttpOnly Cookie，
+// 响应体中不包含 token，只返回 CSRF token；否则按原方式在响应体中返回 token
+func respondAuth(c *gin.Context, user model.User, accessToken string, refreshToken string) {
+	data := model.AuthResponse{User: user, AccessToken: accessToken, RefreshToken: refreshToken}
+	if auth.WantsCookieSession(c.Request) {
+		csrf, err := auth.SetSessionCookies(c.Writer, accessToken, accessTokenTTL, refreshToken, refreshTokenTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "Token生成失败", Code: 500})
+			return
+		}
+		data = model.AuthResponse{User: user, CSRFToken: csrf}
+	}
+	c.JSON(http.StatusOK, model.Response[model.AuthResponse]{Success: true, Code: 200, Data: data})
+}
+
+// refreshTokenFromRequest 读取待刷新/撤销的 refresh token：优先请求体，其次 refresh_token Cookie。
+// 使用 Cookie 时必须通过 CSRF 校验，失败时已写入错误响应并返回 ok=false
+func refreshTokenFromRequest(c *gin.Context, bodyToken string) (token string, fromCookie bool, ok bool) {
+	if bodyToken != "" {
+		return bodyToken, false, true
+	}
+	token = auth.RefreshTokenFromCookie(c.Request)
+	if token == "" {
+		return "", false, true
+	}
+	if !auth.CheckCSRF(c.Request) {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "CSRF token无效", Code: 403})
+		return "", true, false
+	}
+	return token, true, true
+}
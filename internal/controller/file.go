@@ -91,6 +91,7 @@ func UploadFile(c *gin.Context) {
 		S3URL:     s3URL,
 		Location:  req.Location,
 		RelatedID: req.RelatedID,
+		UserID:    optionalUserID(c),
 	}
 
 	db := database.GetDB()
@@ -146,6 +147,7 @@ func CreateFile(c *gin.Context) {
 		S3URL:     s3URL,
 		Location:  req.Location,
 		RelatedID: req.RelatedID,
+		UserID:    optionalUserID(c),
 	}
 
 	db := database.GetDB()
@@ -428,6 +430,14 @@ func getContentType(ext string) string {
 	}
 }
 
+// optionalUserID 当前登录用户的 ID，API Key 调用时返回 nil
+func optionalUserID(c *gin.Context) *int {
+	if userID := c.GetInt("user_id"); userID != 0 {
+		return &userID
+	}
+	return nil
+}
+
 // 辅助函数：从 S3 URL 提取 S3 Key
 func extractS3Key(s3URL string) string {
 	// S3 URL 格式通常是: https://bucket.s3.region.amazonaws.com/key
@@ -0,0 +1,449 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/markbates/goth"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrIdentityEmailConflict 邮箱已被其他账号使用且不满足自动关联条件
+	ErrIdentityEmailConflict = errors.New("email already registered")
+	errIdentityLinkedToOther = errors.New("identity linked to another user")
+	errUnsupportedProvider   = errors.New("unsupported provider")
+)
+
+// ExternalIdentity 第三方登录提供方确认过的用户身份
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Avatar        string
+}
+
+// legacyIdentityColumn users 表中保存该提供方 ID 的旧字段
+func legacyIdentityColumn(provider string) string {
+	switch provider {
+	case "google":
+		return "google_id"
+	case "apple":
+		return "apple_id"
+	}
+	return ""
+}
+
+// canAutoLinkByEmail 判断第三方身份能否按邮箱自动关联到已有账号：
+// 提供方必须确认过该邮箱，且不能是 Apple 中转邮箱（只属于对应的 Apple ID）
+func canAutoLinkByEmail(ext ExternalIdentity) bool {
+	return ext.Email != "" && ext.EmailVerified && !auth.IsApplePrivateRelay(ext.Email)
+}
+
+// ResolveIdentityUser 根据第三方身份查找、关联或创建用户，规则如下：
+//  1. (provider, subject) 已关联 → 直接返回该用户
+//  2. users 表旧字段 google_id / apple_id 匹配 → 补建关联
+//  3. 存在同邮箱账号 → 满足 canAutoLinkByEmail 时自动关联，否则返回 ErrIdentityEmailConflict；
+//     若该账号仍是未验证(pending)的密码账号，可能是他人抢注，关联时清除其密码并撤销已签发的 token
+//  4. 其余情况创建新用户
+//
+// 只有 pending 账号会在此时被激活，停用或注销的账号保持原状态，登录时返回 403
+func ResolveIdentityUser(db *gorm.DB, ext ExternalIdentity) (model.User, error) {
+	ext.Email = strings.ToLower(strings.TrimSpace(ext.Email))
+
+	var user model.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var identity model.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", ext.Provider, ext.Subject).First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&identity).Updates(map[string]interface{}{
+				"email":          ext.Email,
+				"email_verified": ext.EmailVerified,
+				"last_login_at":  now,
+				"updated_at":     now,
+			}).Error; err != nil {
+				return err
+			}
+			return fillUserProfile(tx, &user, ext, nil)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if col := legacyIdentityColumn(ext.Provider); col != "" {
+			err := tx.Where(col+" = ?", ext.Subject).First(&user).Error
+			if err == nil {
+				if err := createIdentity(tx, user.UserID, ext); err != nil {
+					return err
+				}
+				return fillUserProfile(tx, &user, ext, nil)
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		if ext.Email != "" {
+			err := tx.Where("email = ?", ext.Email).First(&user).Error
+			if err == nil {
+				if !canAutoLinkByEmail(ext) {
+					return ErrIdentityEmailConflict
+				}
+				updates := map[string]interface{}{}
+				if col := legacyIdentityColumn(ext.Provider); col != "" {
+					updates[col] = ext.Subject
+				}
+				if user.Status == "pending" {
+					updates["password"] = ""
+					updates["pending_password"] = ""
+					updates["verify_code"] = ""
+					updates["verify_code_expire"] = nil
+					if err := revokeAllUserTokens(tx, user.UserID); err != nil {
+						return err
+					}
+				}
+				if err := createIdentity(tx, user.UserID, ext); err != nil {
+					return err
+				}
+				return fillUserProfile(tx, &user, ext, updates)
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		email := ext.Email
+		if email == "" {
+			// 提供方未返回邮箱时使用占位地址，满足 email 唯一约束
+			email = fmt.Sprintf("%s_%s@users.invalid", ext.Provider, ext.Subject)
+		}
+		user = model.User{
+			Email:    email,
+			Name:     ext.Name,
+			Avatar:   ext.Avatar,
+			Provider: ext.Provider,
+			Status:   "active",
+		}
+		switch ext.Provider {
+		case "google":
+			user.GoogleID = ext.Subject
+		case "apple":
+			user.AppleID = ext.Subject
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return createIdentity(tx, user.UserID, ext)
+	})
+	return user, err
+}
+
+// fillUserProfile 激活待验证(pending)的用户并补全空缺的姓名、头像，extra 为额外需要更新的字段。
+// 已停用或已注销的账号保持原状态不做任何修改，由 completeLogin 拒绝登录
+func fillUserProfile(tx *gorm.DB, user *model.User, ext ExternalIdentity, extra map[string]interface{}) error {
+	if user.Status != "active" && user.Status != "pending" {
+		return nil
+	}
+	updates := map[string]interface{}{}
+	for k, v := range extra {
+		updates[k] = v
+	}
+	if user.Status == "pending" {
+		updates["status"] = "active"
+	}
+	if user.Name == "" && ext.Name != "" {
+		updates["name"] = ext.Name
+	}
+	if user.Avatar == "" && ext.Avatar != "" {
+		updates["avatar"] = ext.Avatar
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	updates["updated_at"] = time.Now()
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+	return tx.First(user, user.UserID).Error
+}
+
+func createIdentity(tx *gorm.DB, userID int, ext ExternalIdentity) error {
+	now := time.Now()
+	return tx.Create(&model.UserIdentity{
+		UserID:        userID,
+		Provider:      ext.Provider,
+		Subject:       ext.Subject,
+		Email:         ext.Email,
+		EmailVerified: ext.EmailVerified,
+		LastLoginAt:   &now,
+	}).Error
+}
+
+// verifyProviderIDToken 校验移动端提交的第三方 ID Token
+func verifyProviderIDToken(provider string, idToken string, nonce string) (ExternalIdentity, error) {
+	switch provider {
+	case "google":
+		info, err := auth.VerifyGoogleIDToken(idToken)
+		if err != nil {
+			return ExternalIdentity{}, err
+		}
+		return ExternalIdentity{
+			Provider:      "google",
+			Subject:       info.Subject,
+			Email:         info.Email,
+			EmailVerified: info.EmailVerified,
+			Name:          info.Name,
+			Avatar:        info.Picture,
+		}, nil
+	case "apple":
+		info, err := auth.VerifyAppleIDToken(idToken, nonce)
+		if err != nil {
+			return ExternalIdentity{}, err
+		}
+		return ExternalIdentity{
+			Provider:      "apple",
+			Subject:       info.Subject,
+			Email:         info.Email,
+			EmailVerified: info.EmailVerified,
+		}, nil
+	case "line":
+		info, err := auth.VerifyLineIDToken(idToken, nonce)
+		if err != nil {
+			return ExternalIdentity{}, err
+		}
+		return ExternalIdentity{
+			Provider: "line",
+			Subject:  info.Subject,
+			Email:    info.Email,
+			Name:     info.Name,
+			Avatar:   info.Picture,
+		}, nil
+	}
+	return ExternalIdentity{}, errUnsupportedProvider
+}
+
+// GothIdentity 把 goth OAuth 回调得到的用户转换为 ExternalIdentity。
+// Google userinfo 通过 verified_email / email_verified 标明邮箱是否已验证，LINE 不提供该字段，视为未验证
+func GothIdentity(u goth.User) ExternalIdentity {
+	verified := false
+	for _, key := range []string{"verified_email", "email_verified"} {
+		switch v := u.RawData[key].(type) {
+		case bool:
+			verified = verified || v
+		case string:
+			verified = verified || v == "true"
+		}
+	}
+	return ExternalIdentity{
+		Provider:      u.Provider,
+		Subject:       u.UserID,
+		Email:         u.Email,
+		EmailVerified: verified,
+		Name:          u.Name,
+		Avatar:        u.AvatarURL,
+	}
+}
+
+// BackfillUserIdentities 把 users 表旧字段 google_id / apple_id 迁移到 user_identities，已存在的跳过
+func BackfillUserIdentities(db *gorm.DB) error {
+	for _, provider := range []string{"google", "apple"} {
+		col := legacyIdentityColumn(provider)
+		var users []model.User
+		if err := db.Where(col + " <> ''").Find(&users).Error; err != nil {
+			return err
+		}
+		for _, u := range users {
+			subject := u.GoogleID
+			if provider == "apple" {
+				subject = u.AppleID
+			}
+			if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.UserIdentity{
+				UserID:   u.UserID,
+				Provider: provider,
+				Subject:  subject,
+				Email:    u.Email,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListIdentities godoc
+// @Summary 获取已关联的第三方登录
+// @Description 获取当前用户关联的全部第三方登录身份
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Response[[]model.UserIdentity]
+// @Failure 401 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/identities [get]
+func ListIdentities(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	db := database.GetDB()
+
+	var identities []model.UserIdentity
+	if err := db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.Response[[]model.UserIdentity]{Success: true, Code: 200, Data: identities})
+}
+
+// LinkIdentity godoc
+// @Summary 关联第三方登录
+// @Description 为当前用户关联 Google / Apple / LINE 账号，需提交对应的 ID Token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param provider path string true "提供方 (google / apple / line)"
+// @Param payload body model.LinkIdentityRequest true "关联请求"
+// @Success 200 {object} model.Response[model.UserIdentity]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 409 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/identities/{provider} [post]
+func LinkIdentity(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	provider := c.Param("provider")
+
+	var req model.LinkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	ext, err := verifyProviderIDToken(provider, req.IdToken, req.Nonce)
+	if errors.Is(err, errUnsupportedProvider) {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "不支持的登录方式", Code: 400})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ %s token验证失败: %v", provider, err)
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "token无效", Code: 400})
+		return
+	}
+
+	db := database.GetDB()
+	var identity model.UserIdentity
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("provider = ? AND subject = ?", ext.Provider, ext.Subject).First(&identity).Error
+		if err == nil {
+			if identity.UserID != userID {
+				return errIdentityLinkedToOther
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		col := legacyIdentityColumn(ext.Provider)
+		if col != "" {
+			var count int64
+			if err := tx.Model(&model.User{}).Where(col+" = ? AND user_id <> ?", ext.Subject, userID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return errIdentityLinkedToOther
+			}
+		}
+		if err := createIdentity(tx, userID, ext); err != nil {
+			return err
+		}
+		if col != "" {
+			if err := tx.Model(&model.User{}).Where("user_id = ? AND ("+col+" IS NULL OR "+col+" = '')", userID).
+				Update(col, ext.Subject).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("provider = ? AND subject = ?", ext.Provider, ext.Subject).First(&identity).Error
+	})
+	if errors.Is(err, errIdentityLinkedToOther) {
+		c.JSON(http.StatusConflict, model.BaseResponse{Success: false, ErrMessage: "该账号已关联其他用户", Code: 409})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "关联失败: " + err.Error(), Code: 500})
+		return
+	}
+
+	recordAuthEvent(c, db, userID, "", model.AuthEventIdentityLink, true, ext.Provider)
+	c.JSON(http.StatusOK, model.Response[model.UserIdentity]{Success: true, Code: 200, Data: identity})
+}
+
+// UnlinkIdentity godoc
+// @Summary 解除第三方登录关联
+// @Description 解除当前用户的某个第三方登录身份，账号至少需要保留一种登录方式
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param id path int true "身份ID"
+// @Success 200 {object} model.BaseResponse
+// @Failure 400 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/identities/{id} [delete]
+func UnlinkIdentity(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	identityID, _ := strconv.Atoi(c.Param("id"))
+
+	db := database.GetDB()
+	var identity model.UserIdentity
+	if err := db.Where("identity_id = ? AND user_id = ?", identityID, userID).First(&identity).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "关联不存在", Code: 404})
+		return
+	}
+
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "用户不存在", Code: 404})
+		return
+	}
+	var count int64
+	db.Model(&model.UserIdentity{}).Where("user_id = ?", userID).Count(&count)
+	if user.Password == "" && count <= 1 {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "请先设置密码或关联其他登录方式", Code: 400})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&identity).Error; err != nil {
+			return err
+		}
+		if col := legacyIdentityColumn(identity.Provider); col != "" {
+			return tx.Model(&model.User{}).Where("user_id = ? AND "+col+" = ?", userID, identity.Subject).
+				Update(col, "").Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	recordAuthEvent(c, db, userID, "", model.AuthEventIdentityUnlink, true, identity.Provider)
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, Code: 200})
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LineAuth godoc
+// @Summary LINE 登录
+// @Description 校验 LINE SDK 返回的 ID Token，按关联身份查找用户；首次登录时创建新用户。LINE 不提供邮箱验证状态，同邮箱的已有账号需登录后在账号设置中关联 LINE
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.LineAuthRequest true "LINE 登录请求"
+// @Success 200 {object} model.Response[model.AuthResponse]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 409 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/line [post]
+func LineAuth(c *gin.Context) {
+	var req model.LineAuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+
+	ext, err := verifyProviderIDToken("line", req.IdToken, req.Nonce)
+	if err != nil {
+		log.Printf("❌ LINE token验证失败: %v", err)
+		recordAuthEvent(c, database.GetDB(), 0, "", model.AuthEventLoginFailed, false, "line: invalid token")
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "LINE token无效", Code: 400})
+		return
+	}
+
+	db := database.GetDB()
+	user, err := ResolveIdentityUser(db, ext)
+	if errors.Is(err, ErrIdentityEmailConflict) {
+		recordAuthEvent(c, db, 0, ext.Email, model.AuthEventLoginFailed, false, "line: email_conflict")
+		c.JSON(http.StatusConflict, model.BaseResponse{Success: false, ErrMessage: "该邮箱已注册，请登录后在账号设置中关联 LINE", Code: 409})
+		return
+	}
+	if err != nil {
+		log.Printf("❌ LINE用户登录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "用户注册失败", Code: 500})
+		return
+	}
+
+	completeLogin(c, db, user, auth.AMRFederated)
+}
@@ -0,0 +1,513 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+	"ar-backend/pkg/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+const (
+	mfaChallengeTTL    = 5 * time.Minute // 第一步登录后完成第二步的时限
+	mfaMaxFailures     = 5               // 连续输错验证码的次数上限，超过后临时锁定
+	mfaSkew            = 1               // 允许前后各一个时间步的时钟偏差
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+var (
+	errMFACodeInvalid = errors.New("mfa code invalid")
+	errMFANotEnabled  = errors.New("mfa not enabled")
+
+	errTooManyMFAFailures = errors.New("too many mfa failures")
+)
+
+// loadUserMFA 读取用户的两步验证配置，未配置时返回 gorm.ErrRecordNotFound
+func loadUserMFA(db *gorm.DB, userID int) (*model.UserMFA, error) {
+	var mfa model.UserMFA
+	if err := db.Where("user_id = ?", userID).First(&mfa).Error; err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+// userMFAEnabled 判断用户是否已开启两步验证
+func userMFAEnabled(db *gorm.DB, userID int) (bool, error) {
+	var count int64
+	err := db.Model(&model.UserMFA{}).Where("user_id = ? AND enabled = true", userID).Count(&count).Error
+	return count > 0, err
+}
+
+// completeLogin 第一步认证（密码、第三方登录等）通过后调用：
+// 已开启两步验证的账号只返回挑战 token，否则直接签发 token
+func completeLogin(c *gin.Context, db *gorm.DB, user model.User, amr ...string) {
+	if user.Status != "active" {
+		respondInactiveUser(c, user)
+		return
+	}
+	enabled, err := userMFAEnabled(db, user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	if enabled {
+		mfaToken, err := auth.SignMFAChallenge(user.UserID, amr, mfaChallengeTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "Token生成失败", Code: 500})
+			return
+		}
+		recordAuthEvent(c, db, user.UserID, user.Email, model.AuthEventMFAChallenge, true, strings.Join(amr, ","))
+		c.JSON(http.StatusOK, model.Response[model.AuthResponse]{
+			Success: true,
+			Code:    200,
+			Data:    model.AuthResponse{MFARequired: true, MFAToken: mfaToken},
+		})
+		return
+	}
+
+	meta := newSessionMeta(c)
+	meta.AMR = amr
+	accessToken, refreshToken, err := issueTokenPair(db, user.UserID, "", meta)
+	if errors.Is(err, errUserNotActive) {
+		// 账号在检查之后被停用
+		respondInactiveUser(c, user)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "Token生成失败", Code: 500})
+		return
+	}
+	recordAuthEvent(c, db, user.UserID, user.Email, model.AuthEventLogin, true, strings.Join(amr, ","))
+	respondAuth(c, user, accessToken, refreshToken)
+}
+
+// normalizeRecoveryCode 去掉分隔符和空格并转为小写
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// generateRecoveryCodes 生成一组恢复码，格式为 xxxxx-xxxxx
+func generateRecoveryCodes() ([]string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))[:recoveryCodeLength]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+	}
+	return codes, nil
+}
+
+// replaceRecoveryCodes 作废旧的恢复码并保存新的哈希，返回明文
+func replaceRecoveryCodes(tx *gorm.DB, userID int) ([]string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&model.MFARecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]model.MFARecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, model.MFARecoveryCode{UserID: userID, CodeHash: hashSecret(normalizeRecoveryCode(code))})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// checkTOTP 校验 TOTP 验证码，通过后记录时间步，同一验证码不能再次使用
+func checkTOTP(db *gorm.DB, mfa *model.UserMFA, code string) error {
+	secret, err := auth.DecryptMFASecret(mfa.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), mfaSkew)
+	if !ok {
+		return errMFACodeInvalid
+	}
+	// 条件更新防止并发请求重复使用同一验证码
+	result := db.Model(&model.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", mfa.UserID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errMFACodeInvalid
+	}
+	return nil
+}
+
+// useRecoveryCode 核销一个恢复码
+func useRecoveryCode(db *gorm.DB, userID int, code string) error {
+	result := db.Model(&model.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashSecret(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errMFACodeInvalid
+	}
+	return nil
+}
+
+// verifyMFACode 校验已开启两步验证的用户提交的 TOTP 验证码或恢复码，返回使用的认证方式
+func verifyMFACode(db *gorm.DB, userID int, code string, allowRecovery bool) (string, error) {
+	mfa, err := loadUserMFA(db, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !mfa.Enabled) {
+		return "", errMFANotEnabled
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// 连续输错次数过多时临时锁定，防止穷举 6 位验证码
+	store := auth.Logins().Store
+	key := auth.MFAAttemptKey(userID)
+	attempt, err := store.Get(key)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return "", errTooManyMFAFailures
+	}
+
+	method := auth.AMROTP
+	err = checkTOTP(db, mfa, code)
+	if errors.Is(err, errMFACodeInvalid) && allowRecovery && len(normalizeRecoveryCode(code)) == recoveryCodeLength {
+		method = auth.AMRRecovery
+		err = useRecoveryCode(db, userID, code)
+	}
+	if errors.Is(err, errMFACodeInvalid) {
+		policy := auth.DefaultLoginPolicy()
+		if a, recErr := store.RecordFailure(key, now, policy.Window); recErr == nil && a.Failures >= mfaMaxFailures {
+			_ = store.Lock(key, now.Add(policy.LockDuration))
+		}
+		return "", err
+	}
+	if err != nil {
+		return "", err
+	}
+	_ = store.Reset(key)
+	return method, nil
+}
+
+// respondMFAError 把验证码校验错误转换为响应
+func respondMFAError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errMFACodeInvalid):
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "验证码错误", Code: 401})
+	case errors.Is(err, errMFANotEnabled):
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "未开启两步验证", Code: 400})
+	case errors.Is(err, errTooManyMFAFailures):
+		c.JSON(http.StatusTooManyRequests, model.BaseResponse{Success: false, ErrMessage: "验证码错误次数过多，请稍后再试", Code: 429})
+	default:
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+	}
+}
+
+// VerifyMFA godoc
+// @Summary 两步验证登录
+// @Description 使用登录返回的 mfa_token 和验证器中的 6 位验证码（或恢复码）完成登录
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.MFAVerifyRequest true "两步验证请求"
+// @Success 200 {object} model.Response[model.AuthResponse]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
+// @Failure 429 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/mfa/verify [post]
+func VerifyMFA(c *gin.Context) {
+	var req model.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	challenge, err := auth.ParseMFAChallenge(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "登录已过期，请重新登录", Code: 401})
+		return
+	}
+
+	db := database.GetDB()
+	method, err := verifyMFACode(db, challenge.ChallengeUserID, req.Code, true)
+	if err != nil {
+		recordAuthEvent(c, db, challenge.ChallengeUserID, "", model.AuthEventLoginFailed, false, "mfa: "+err.Error())
+		respondMFAError(c, err)
+		return
+	}
+
+	var user model.User
+	if err := db.First(&user, challenge.ChallengeUserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "用户不存在", Code: 401})
+		return
+	}
+	// 账号可能在第一步登录之后被停用
+	if user.Status != "active" {
+		respondInactiveUser(c, user)
+		return
+	}
+
+	meta := newSessionMeta(c)
+	meta.AMR = append(append([]string{}, challenge.AMR...), method, auth.AMRMFA)
+	accessToken, refreshToken, err := issueTokenPair(db, user.UserID, "", meta)
+	if errors.Is(err, errUserNotActive) {
+		// 账号在检查之后被停用
+		respondInactiveUser(c, user)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "Token生成失败", Code: 500})
+		return
+	}
+	recordAuthEvent(c, db, user.UserID, user.Email, model.AuthEventLogin, true, strings.Join(meta.AMR, ","))
+	respondAuth(c, user, accessToken, refreshToken)
+}
+
+// GetMFAStatus godoc
+// @Summary 两步验证状态
+// @Description 查询当前用户是否开启两步验证、角色是否强制要求以及剩余恢复码数量
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} model.Response[model.MFAStatusResponse]
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/mfa [get]
+func GetMFAStatus(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	db := database.GetDB()
+
+	status := model.MFAStatusResponse{Required: auth.RoleRequiresMFA(c.GetString("role"))}
+	mfa, err := loadUserMFA(db, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	if mfa != nil && mfa.Enabled {
+		status.Enabled = true
+		status.ConfirmedAt = mfa.ConfirmedAt
+		db.Model(&model.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&status.RemainingRecoveryCodes)
+	}
+	c.JSON(http.StatusOK, model.Response[model.MFAStatusResponse]{Success: true, Code: 200, Data: status})
+}
+
+// EnrollMFA godoc
+// @Summary 绑定验证器
+// @Description 生成新的 TOTP 密钥，返回 otpauth 地址和二维码。需调用 /api/auth/mfa/confirm 提交验证码后才会生效
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} model.Response[model.MFAEnrollResponse]
+// @Failure 409 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/mfa/enroll [post]
+func EnrollMFA(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	db := database.GetDB()
+
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "用户不存在", Code: 404})
+		return
+	}
+	if enabled, err := userMFAEnabled(db, userID); err != nil || enabled {
+		c.JSON(http.StatusConflict, model.BaseResponse{Success: false, ErrMessage: "已开启两步验证，如需更换请先关闭", Code: 409})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	encrypted, err := auth.EncryptMFASecret(secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	uri := totp.KeyURI(auth.MFAIssuer(), user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+
+	// 未确认的密钥直接覆盖
+	now := time.Now()
+	if err := db.Save(&model.UserMFA{UserID: userID, Secret: encrypted, CreatedAt: now, UpdatedAt: &now}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response[model.MFAEnrollResponse]{
+		Success: true,
+		Code:    200,
+		Data: model.MFAEnrollResponse{
+			Secret:     secret,
+			OtpauthURI: uri,
+			QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+		},
+	})
+}
+
+// ConfirmMFA godoc
+// @Summary 确认绑定验证器
+// @Description 提交验证器中的验证码开启两步验证，返回一次性恢复码（只显示这一次）。开启后需重新登录以获得两步验证会话
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.MFACodeRequest true "验证码"
+// @Success 200 {object} model.Response[model.MFARecoveryCodesResponse]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/mfa/confirm [post]
+func ConfirmMFA(c *gin.Context) {
+	var req model.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	userID := c.GetInt("user_id")
+	db := database.GetDB()
+
+	mfa, err := loadUserMFA(db, userID)
+	if err != nil || mfa.Enabled {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "请先调用 /api/auth/mfa/enroll 生成密钥", Code: 400})
+		return
+	}
+	if err := checkTOTP(db, mfa, req.Code); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	var codes []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&model.UserMFA{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+			"enabled":      true,
+			"confirmed_at": now,
+			"updated_at":   now,
+		}).Error; err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	recordAuthEvent(c, db, userID, "", model.AuthEventMFAEnable, true, "")
+	c.JSON(http.StatusOK, model.Response[model.MFARecoveryCodesResponse]{Success: true, Code: 200, Data: model.MFARecoveryCodesResponse{RecoveryCodes: codes}})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary 重新生成恢复码
+// @Description 提交验证码后作废旧的恢复码并生成新的一组
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.MFACodeRequest true "验证码"
+// @Success 200 {object} model.Response[model.MFARecoveryCodesResponse]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req model.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	userID := c.GetInt("user_id")
+	db := database.GetDB()
+	if _, err := verifyMFACode(db, userID, req.Code, false); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	recordAuthEvent(c, db, userID, "", model.AuthEventMFARecoveryCode, true, "")
+	c.JSON(http.StatusOK, model.Response[model.MFARecoveryCodesResponse]{Success: true, Code: 200, Data: model.MFARecoveryCodesResponse{RecoveryCodes: codes}})
+}
+
+// DisableMFA godoc
+// @Summary 关闭两步验证
+// @Description 提交验证码或恢复码关闭两步验证。角色强制要求两步验证时不能关闭
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.MFACodeRequest true "验证码"
+// @Success 200 {object} model.BaseResponse
+// @Failure 400 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/mfa/disable [post]
+func DisableMFA(c *gin.Context) {
+	var req model.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	if role := c.GetString("role"); auth.RoleRequiresMFA(role) {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: fmt.Sprintf("角色 %s 必须开启两步验证", role), Code: 403})
+		return
+	}
+	userID := c.GetInt("user_id")
+	db := database.GetDB()
+	if _, err := verifyMFACode(db, userID, req.Code, true); err != nil {
+		respondMFAError(c, err)
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.UserMFA{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	recordAuthEvent(c, db, userID, "", model.AuthEventMFADisable, true, "")
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, Code: 200})
+}
@@ -0,0 +1,306 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/markbates/goth/gothic"
+	"gorm.io/gorm"
+)
+
+const (
+	authorizationCodeTTL = time.Minute // 授权码有效期，只用于回调后立即兑换
+	oauthSessionName     = "oauth_session"
+)
+
+var errInvalidAuthorizationCode = errors.New("invalid authorization code")
+
+// getDefaultFrontendURL 获取默认前端 URL
+func getDefaultFrontendURL() string {
+	if u := os.Getenv("FRONTEND_URL"); u != "" {
+		return u
+	}
+	if os.Getenv("ENVIRONMENT") == "production" {
+		return "https://www.ifoodme.com/"
+	}
+	return "http://localhost:3001/"
+}
+
+// pkceChallengeS256 按 RFC 7636 计算 S256 code_challenge
+func pkceChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// verifyPKCE 校验 code_verifier 与授权时提交的 S256 code_challenge 是否匹配
+func verifyPKCE(verifier string, challenge string) bool {
+	if verifier == "" || challenge == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(pkceChallengeS256(verifier)), []byte(challenge)) == 1
+}
+
+// appendQuery 在跳转地址上追加查询参数，保留地址中已有的参数
+func appendQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if u.Path == "" && u.Host != "" {
+		u.Path = "/"
+	}
+	q := u.Query()
+	for k, vs := range params {
+		for _, v := range vs {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// withProvider 把 provider 写入请求上下文，供 gothic 识别
+func withProvider(c *gin.Context) (string, *http.Request) {
+	provider := c.Param("provider")
+	if provider == "" {
+		provider = "google"
+	}
+	return provider, c.Request.WithContext(context.WithValue(c.Request.Context(), "provider", provider))
+}
+
+// BeginOAuth godoc
+// @Summary 开始第三方 OAuth 认证
+// @Description 重定向到第三方登录页面。客户端必须携带 PKCE code_challenge (S256)，回调后凭授权码和 code_verifier 调用 /api/auth/token 换取 token
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "登录方式，如 google、line"
+// @Param redirect query string false "登录完成后的跳转地址，必须已在客户端登记，支持深度链接(如: travelview://google-auth-callback)"
+// @Param client_id query string false "客户端 ID，如 web、ios、android、dev"
+// @Param code_challenge query string true "PKCE code_challenge"
+// @Param code_challenge_method query string true "固定为 S256"
+// @Param state query string false "客户端状态，回调时原样返回"
+// @Success 302 {string} string "重定向到第三方登录页面"
+// @Failure 400 {object} model.BaseResponse
+// @Router /api/auth/{provider} [get]
+func BeginOAuth(c *gin.Context) {
+	_, r := withProvider(c)
+
+	challenge := c.Query("code_challenge")
+	if challenge == "" || len(challenge) > 128 || c.Query("code_challenge_method") != "S256" {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "缺少 code_challenge 或 code_challenge_method 不是 S256", Code: 400})
+		return
+	}
+
+	// 支持两种参数名：redirect 和 redirect_uri
+	redirectURL := c.Query("redirect")
+	if redirectURL == "" {
+		redirectURL = c.Query("redirect_uri")
+	}
+	if redirectURL == "" {
+		redirectURL = getDefaultFrontendURL()
+	}
+
+	// 回跳地址必须属于已登记的客户端
+	client, err := auth.Clients().ResolveRedirect(c.Query("client_id"), redirectURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "回跳地址未登记: " + redirectURL, Code: 400})
+		return
+	}
+
+	// 登录参数保存到 session 中，回调时使用
+	session, err := gothic.Store.Get(r, oauthSessionName)
+	if err != nil {
+		log.Printf("获取 oauth session 失败: %v", err)
+	}
+	session.Values["redirect_url"] = redirectURL
+	session.Values["client_id"] = client.ClientID
+	session.Values["code_challenge"] = challenge
+	session.Values["client_state"] = c.Query("state")
+	if err := session.Save(r, c.Writer); err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "保存登录状态失败", Code: 500})
+		return
+	}
+
+	gothic.BeginAuthHandler(c.Writer, r)
+}
+
+// OAuthCallback godoc
+// @Summary 第三方 OAuth 回调处理
+// @Description 完成第三方登录后签发一次性授权码，重定向到前端或 App 深度链接并携带 code 和 state，URL 中不包含 token
+// @Tags Auth
+// @Produce json
+// @Param provider path string true "登录方式，如 google、line"
+// @Success 302 {string} string "重定向到前端页面，并携带授权码"
+// @Router /api/auth/{provider}/callback [get]
+func OAuthCallback(c *gin.Context) {
+	provider, r := withProvider(c)
+
+	session, err := gothic.Store.Get(r, oauthSessionName)
+	if err != nil {
+		log.Printf("获取 oauth session 失败: %v", err)
+	}
+	redirectURL, _ := session.Values["redirect_url"].(string)
+	challenge, _ := session.Values["code_challenge"].(string)
+	clientState, _ := session.Values["client_state"].(string)
+	clientID, _ := session.Values["client_id"].(string)
+
+	// session 中的地址已在 BeginOAuth 校验过，这里再次确认，缺失时回到默认前端
+	if _, err := auth.Clients().ResolveRedirect(clientID, redirectURL); err != nil {
+		redirectURL = getDefaultFrontendURL()
+		clientID = ""
+	}
+
+	// 登录参数只能使用一次
+	delete(session.Values, "redirect_url")
+	delete(session.Values, "client_id")
+	delete(session.Values, "code_challenge")
+	delete(session.Values, "client_state")
+	if err := session.Save(r, c.Writer); err != nil {
+		log.Printf("清理 oauth session 失败: %v", err)
+	}
+
+	fail := func(reason string) {
+		params := url.Values{"error": {reason}}
+		if clientState != "" {
+			params.Set("state", clientState)
+		}
+		c.Redirect(http.StatusFound, appendQuery(redirectURL, params))
+	}
+
+	if challenge == "" || clientID == "" {
+		fail("invalid_request")
+		return
+	}
+
+	db := database.GetDB()
+	user, err := gothic.CompleteUserAuth(c.Writer, r)
+	if err != nil {
+		log.Printf("OAuth 回调认证失败 (%s): %v", provider, err)
+		recordAuthEvent(c, db, 0, "", model.AuthEventLoginFailed, false, provider+": access_denied")
+		fail("access_denied")
+		return
+	}
+
+	userInDB, err := ResolveIdentityUser(db, GothIdentity(user))
+	if errors.Is(err, ErrIdentityEmailConflict) {
+		recordAuthEvent(c, db, 0, user.Email, model.AuthEventLoginFailed, false, provider+": email_conflict")
+		fail("email_conflict")
+		return
+	}
+	if err != nil {
+		log.Printf("OAuth 用户登录失败 (%s): %v", provider, err)
+		fail("server_error")
+		return
+	}
+
+	code, err := generateSecureToken()
+	if err != nil {
+		fail("server_error")
+		return
+	}
+	if err := db.Create(&model.AuthorizationCode{
+		CodeHash:      hashSecret(code),
+		UserID:        userInDB.UserID,
+		ClientID:      clientID,
+		Provider:      provider,
+		CodeChallenge: challenge,
+		RedirectURI:   redirectURL,
+		ExpiresAt:     time.Now().Add(authorizationCodeTTL),
+	}).Error; err != nil {
+		log.Printf("保存授权码失败: %v", err)
+		fail("server_error")
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if clientState != "" {
+		params.Set("state", clientState)
+	}
+	c.Redirect(http.StatusFound, appendQuery(redirectURL, params))
+}
+
+// consumeAuthorizationCode 核销授权码并校验 PKCE，返回授权码对应的用户。
+// 授权码先被标记为已使用再校验，校验失败的授权码同样作废，防止被反复猜测
+func consumeAuthorizationCode(db *gorm.DB, req model.TokenExchangeRequest) (int, error) {
+	var authCode model.AuthorizationCode
+	if err := db.Where("code_hash = ? AND used_at IS NULL AND expires_at > ?", hashSecret(req.Code), time.Now()).
+		First(&authCode).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, errInvalidAuthorizationCode
+		}
+		return 0, err
+	}
+
+	// 条件更新保证授权码只能被使用一次
+	result := db.Model(&model.AuthorizationCode{}).
+		Where("code_id = ? AND used_at IS NULL", authCode.CodeID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, errInvalidAuthorizationCode
+	}
+
+	if !verifyPKCE(req.CodeVerifier, authCode.CodeChallenge) {
+		return 0, errInvalidAuthorizationCode
+	}
+	if req.ClientID != "" && req.ClientID != authCode.ClientID {
+		return 0, errInvalidAuthorizationCode
+	}
+	if req.RedirectURI != "" && req.RedirectURI != authCode.RedirectURI {
+		return 0, errInvalidAuthorizationCode
+	}
+	return authCode.UserID, nil
+}
+
+// ExchangeToken godoc
+// @Summary 授权码换取 token
+// @Description 使用 OAuth 回调返回的一次性授权码和 PKCE code_verifier 换取 access token 和 refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.TokenExchangeRequest true "授权码兑换请求"
+// @Success 200 {object} model.Response[model.AuthResponse]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/token [post]
+func ExchangeToken(c *gin.Context) {
+	var req model.TokenExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+
+	db := database.GetDB()
+	userID, err := consumeAuthorizationCode(db, req)
+	if errors.Is(err, errInvalidAuthorizationCode) {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "授权码无效或已过期", Code: 400})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+
+	var user model.User
+	if err := db.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+
+	completeLogin(c, db, user, auth.AMRFederated)
+}
@@ -0,0 +1,35 @@
+package controller
+
+import "testing"
+
+func TestVerifyPKCE(t *testing.T) {
+	// RFC 7636 附录 B 的示例
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if got := pkceChallengeS256(verifier); got != challenge {
+		t.Fatalf("pkceChallengeS256 = %s, want %s", got, challenge)
+	}
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"匹配", verifier, challenge, true},
+		{"verifier 错误", verifier + "x", challenge, false},
+		{"plain 方式（verifier 直接作为 challenge）", verifier, verifier, false},
+		{"challenge 大小写不同", verifier, "e9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", false},
+		{"verifier 为空", "", challenge, false},
+		{"challenge 为空", verifier, "", false},
+		{"两者都为空", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyPKCE(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("verifyPKCE(%q, %q) = %v, want %v", tt.verifier, tt.challenge, got, tt.want)
+			}
+		})
+	}
+}
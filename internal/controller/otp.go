@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+	"ar-backend/pkg/mailer"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	otpCodeLength      = 6                // 登录验证码位数，比注册验证码更长
+	otpIPRequestLimit  = 20               // 同一 IP 在窗口期内最多申请的验证码数量
+	otpIPRequestWindow = 15 * time.Minute // 同一 IP 申请次数的统计窗口
+)
+
+// otpRequestMessage 无论邮箱是否已注册都返回相同的提示
+const otpRequestMessage = "验证码已发送，请查收邮箱"
+
+// errLoginCodeUsed 验证码已被并发请求使用
+var errLoginCodeUsed = errors.New("login code already used")
+
+// sendLoginCodeToEmail 发送登录验证码邮件
+func sendLoginCodeToEmail(email string, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	return mailer.SendTemplate(ctx, mailer.Default(), email, "login_code", map[string]any{
+		"Code":          code,
+		"ExpireMinutes": int(verifyCodeTTL.Minutes()),
+	})
+}
+
+// otpIPAllowed 统计同一 IP 申请验证码的次数，超过上限时返回需等待的时间
+func otpIPAllowed(ip string, now time.Time) (time.Duration, error) {
+	store := auth.Logins().Store
+	key := auth.OTPRequestKey(ip)
+	attempt, err := store.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return attempt.LockedUntil.Sub(now), nil
+	}
+	attempt, err = store.RecordFailure(key, now, otpIPRequestWindow)
+	if err != nil {
+		return 0, err
+	}
+	if attempt.Failures > otpIPRequestLimit {
+		until := attempt.LastFailedAt.Add(otpIPRequestWindow)
+		if err := store.Lock(key, until); err != nil {
+			return 0, err
+		}
+		return until.Sub(now), nil
+	}
+	return 0, nil
+}
+
+// RequestLoginCode godoc
+// @Summary 申请邮箱登录验证码
+// @Description 向邮箱发送 6 位登录验证码，无需密码。申请时不创建账号，邮箱未注册时在验证通过后才自动创建。同一邮箱每分钟最多发送一次，同一 IP 每 15 分钟最多 20 次
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.OTPRequest true "验证码申请"
+// @Success 200 {object} model.BaseResponse
+// @Failure 400 {object} model.BaseResponse
+// @Failure 429 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/otp/request [post]
+func RequestLoginCode(c *gin.Context) {
+	var req model.OTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	// 验证码按小写邮箱保存和查找，大小写不同的同一邮箱共享验证码、发送间隔和尝试次数
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !govalidator.IsEmail(email) {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "邮箱格式不正确", Code: 400})
+		return
+	}
+
+	if wait, err := otpIPAllowed(c.ClientIP(), time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	} else if wait > 0 {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, model.BaseResponse{Success: false, ErrMessage: "发送过于频繁，请稍后再试", Code: 429})
+		return
+	}
+
+	db := database.GetDB()
+	now := time.Now()
+	var existing model.LoginCode
+	err := db.Where("email = ?", email).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	attempts := 0
+	if err == nil {
+		if wait := existing.SentAt.Add(verifyResendInterval).Sub(now); wait > 0 {
+			c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, model.BaseResponse{Success: false, ErrMessage: "发送过于频繁，请稍后再试", Code: 429})
+			return
+		}
+		// 与注册验证码相同，重新发送不清零尝试次数，距上次发送足够久后才清零
+		if now.Sub(existing.SentAt) < verifyAttemptWindow {
+			attempts = existing.Attempts
+		}
+	}
+
+	code, err := generateNumericCode(otpCodeLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "验证码生成失败", Code: 500})
+		return
+	}
+	// 只保存验证码哈希，不创建用户，验证通过后才创建或激活账号
+	loginCode := model.LoginCode{
+		Email:     email,
+		CodeHash:  hashSecret(code),
+		ExpiresAt: now.Add(verifyCodeTTL),
+		SentAt:    now,
+		Attempts:  attempts,
+	}
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&loginCode).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "验证码保存失败: " + err.Error(), Code: 500})
+		return
+	}
+	// 顺带清理早已过期的验证码
+	if err := db.Where("expires_at < ?", now.Add(-verifyAttemptWindow)).Delete(&model.LoginCode{}).Error; err != nil {
+		log.Printf("清理过期登录验证码失败: %v", err)
+	}
+
+	if err := sendLoginCodeToEmail(email, code); err != nil {
+		log.Printf("发送登录验证码邮件失败 (%s): %v", email, err)
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "验证码邮件发送失败", Code: 500})
+		return
+	}
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, ErrMessage: otpRequestMessage, Code: 200})
+}
+
+// VerifyLoginCode godoc
+// @Summary 邮箱验证码登录
+// @Description 使用邮件中的登录验证码登录，邮箱未注册时自动创建账号，待验证账号在此时激活。1 小时内重新发送的验证码共享 5 次尝试机会，连续失败会按账号锁定
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.OTPVerifyRequest true "验证码登录"
+// @Success 200 {object} model.Response[model.AuthResponse]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 429 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/otp/verify [post]
+func VerifyLoginCode(c *gin.Context) {
+	var req model.OTPVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	guard := auth.Logins()
+	ip := c.ClientIP()
+	wait, err := guard.Check(email, ip, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	db := database.GetDB()
+	if wait > 0 {
+		recordAuthEvent(c, db, 0, email, model.AuthEventLoginFailed, false, "eml: locked")
+		c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, model.BaseResponse{Success: false, ErrMessage: "登录尝试过于频繁，请稍后再试", Code: 429})
+		return
+	}
+
+	var loginCode model.LoginCode
+	if err := db.Where("email = ?", email).First(&loginCode).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+			return
+		}
+		_ = guard.RecordFailure(email, ip, time.Now())
+		recordAuthEvent(c, db, 0, email, model.AuthEventLoginFailed, false, "eml: no code")
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "验证码错误或已过期", Code: 401})
+		return
+	}
+	if time.Now().After(loginCode.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "验证码错误或已过期", Code: 401})
+		return
+	}
+	// 先以条件更新占用一次尝试机会，并发请求也无法超过次数限制
+	result := db.Model(&model.LoginCode{}).
+		Where("email = ? AND attempts < ?", email, verifyCodeMaxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: result.Error.Error(), Code: 500})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusTooManyRequests, model.BaseResponse{Success: false, ErrMessage: "尝试次数过多，请稍后重新获取验证码", Code: 429})
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(strings.TrimSpace(req.Code))), []byte(loginCode.CodeHash)) != 1 {
+		_ = guard.RecordFailure(email, ip, time.Now())
+		recordAuthEvent(c, db, 0, email, model.AuthEventLoginFailed, false, "eml: wrong code")
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "验证码错误或已过期", Code: 401})
+		return
+	}
+
+	var user model.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 以验证码哈希为条件删除，保证验证码只能被使用一次
+		result := tx.Where("email = ? AND code_hash = ?", email, loginCode.CodeHash).Delete(&model.LoginCode{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errLoginCodeUsed
+		}
+
+		// 以前注册的账号可能保存了大小写混合的邮箱，忽略大小写查找，避免重复创建账号
+		err := tx.Where("LOWER(email) = ?", email).First(&user).Error
+		if err == gorm.ErrRecordNotFound {
+			// 邮箱所有权已验证，此时才创建账号
+			user = model.User{Email: email, Provider: "email", Status: "active"}
+			return tx.Create(&user).Error
+		}
+		if err != nil {
+			return err
+		}
+		if user.Status != "pending" {
+			return nil
+		}
+		updates := map[string]interface{}{
+			"status":             "active",
+			"verify_code":        "",
+			"verify_code_expire": nil,
+			"verify_attempts":    0,
+			"pending_password":   "",
+			"updated_at":         time.Now(),
+		}
+		if user.Password != "" {
+			// 未验证的密码账号可能是他人抢注，邮箱所有者登录后清除其密码并撤销已签发的 token
+			updates["password"] = ""
+			if err := revokeAllUserTokens(tx, user.UserID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&model.User{}).Where("user_id = ? AND status = ?", user.UserID, "pending").Updates(updates).Error
+	})
+	if err == errLoginCodeUsed {
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "验证码错误或已过期", Code: 401})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	if err := guard.RecordSuccess(email); err != nil {
+		log.Printf("清除登录失败次数出错: %v", err)
+	}
+
+	if err := db.First(&user, user.UserID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	completeLogin(c, db, user, auth.AMREmailCode)
+}
@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+	"ar-backend/pkg/mailer"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL            = 30 * time.Minute // 重置链接有效期
+	passwordResetResendInterval = time.Minute      // 同一用户两次申请之间的最小间隔
+)
+
+// forgotPasswordMessage 无论邮箱是否存在都返回相同的提示，避免暴露注册信息
+const forgotPasswordMessage = "如果该邮箱已注册，重置密码邮件已发送"
+
+var errInvalidResetToken = errors.New("invalid reset token")
+
+// getPasswordResetURL 获取重置密码页面地址
+func getPasswordResetURL() string {
+	if u := os.Getenv("PASSWORD_RESET_URL"); u != "" {
+		return u
+	}
+	frontendURL := os.Getenv("FRONTEND_URL")
+	if frontendURL == "" {
+		frontendURL = "http://localhost:3001"
+	}
+	return strings.TrimSuffix(frontendURL, "/") + "/reset-password"
+}
+
+// ForgotPassword godoc
+// @Summary 忘记密码
+// @Description 向邮箱密码用户发送一次性重置链接。无论邮箱是否存在均返回相同结果
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.ForgotPasswordRequest true "忘记密码请求"
+// @Success 200 {object} model.BaseResponse
+// @Failure 400 {object} model.BaseResponse
+// @Router /api/auth/password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var req model.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+
+	db := database.GetDB()
+	var user model.User
+	err := db.Where("email = ? AND provider = ?", strings.TrimSpace(req.Email), "email").First(&user).Error
+	if err == nil && user.Password != "" {
+		if err := createAndSendPasswordReset(db, &user); err != nil {
+			log.Printf("创建密码重置令牌失败 (user_id=%d): %v", user.UserID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, ErrMessage: forgotPasswordMessage, Code: 200})
+}
+
+// createAndSendPasswordReset 生成重置令牌并异步发送邮件
+func createAndSendPasswordReset(db *gorm.DB, user *model.User) error {
+	// 限制发送频率，静默忽略过于频繁的请求
+	var recent int64
+	db.Model(&model.PasswordResetToken{}).
+		Where("user_id = ? AND created_at > ?", user.UserID, time.Now().Add(-passwordResetResendInterval)).
+		Count(&recent)
+	if recent > 0 {
+		return nil
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		return err
+	}
+	if err := db.Create(&model.PasswordResetToken{
+		UserID:    user.UserID,
+		TokenHash: hashSecret(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}).Error; err != nil {
+		return err
+	}
+
+	resetURL := getPasswordResetURL() + "?token=" + url.QueryEscape(token)
+	email := user.Email
+	// 异步发送，避免响应时间差异暴露邮箱是否存在
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		err := mailer.SendTemplate(ctx, mailer.Default(), email, "reset_password", map[string]any{
+			"ResetURL":      resetURL,
+			"ExpireMinutes": int(passwordResetTTL.Minutes()),
+		})
+		if err != nil {
+			log.Printf("发送重置密码邮件失败 (%s): %v", email, err)
+		}
+	}()
+	return nil
+}
+
+// ResetPassword godoc
+// @Summary 重置密码
+// @Description 使用邮件中的一次性令牌设置新密码，成功后该用户所有 refresh token 失效
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.ResetPasswordRequest true "重置密码请求"
+// @Success 200 {object} model.BaseResponse
+// @Failure 400 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var req model.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+	if len(req.Password) < 6 {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "密码长度不能少于6位", Code: 400})
+		return
+	}
+
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "密码加密失败", Code: 500})
+		return
+	}
+
+	db := database.GetDB()
+	var resetUserID int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var resetToken model.PasswordResetToken
+		if err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashSecret(req.Token), time.Now()).
+			First(&resetToken).Error; err != nil {
+			return errInvalidResetToken
+		}
+
+		now := time.Now()
+		// 条件更新保证令牌只能被使用一次
+		result := tx.Model(&model.PasswordResetToken{}).
+			Where("reset_id = ? AND used_at IS NULL", resetToken.ResetID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidResetToken
+		}
+		resetUserID = resetToken.UserID
+
+		if err := tx.Model(&model.User{}).Where("user_id = ?", resetToken.UserID).Updates(map[string]interface{}{
+			"password":   string(hashedPwd),
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// 同一用户的其他未使用重置令牌一并作废
+		if err := tx.Model(&model.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", resetToken.UserID).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+
+		// 撤销该用户的所有 refresh token，强制所有设备重新登录
+		return tx.Model(&model.RefreshToken{}).
+			Where("user_id = ? AND revoked = false", resetToken.UserID).
+			Update("revoked", true).Error
+	})
+	if err == nil {
+		// access token 同样立即失效
+		now := time.Now()
+		auth.AccessDenylist.Revoke(auth.UserKey(resetUserID), now, now.Add(accessTokenTTL))
+	}
+	if errors.Is(err, errInvalidResetToken) {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "重置链接无效或已过期", Code: 400})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "密码重置失败: " + err.Error(), Code: 500})
+		return
+	}
+	recordAuthEvent(c, db, resetUserID, "", model.AuthEventPasswordReset, true, "")
+
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, Code: 200})
+}
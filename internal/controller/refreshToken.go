@@ -29,7 +29,7 @@ func CreateRefreshToken(c *gin.Context) {
 
 	token := model.RefreshToken{
 		UserID:       req.UserID,
-		RefreshToken: req.RefreshToken,
+		RefreshToken: hashSecret(req.RefreshToken),
 		ExpiresAt:    req.ExpiresAt,
 		Revoked:      req.Revoked,
 	}
@@ -64,6 +64,9 @@ func UpdateRefreshToken(c *gin.Context) {
 		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "Token不存在"})
 		return
 	}
+	if req.RefreshToken != "" {
+		req.RefreshToken = hashSecret(req.RefreshToken)
+	}
 	db.Model(&token).Updates(req)
 	c.JSON(http.StatusOK, model.BaseResponse{Success: true})
 }
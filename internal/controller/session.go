@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions godoc
+// @Summary 获取登录会话列表
+// @Description 获取当前用户所有有效的登录会话（设备、IP、最近使用时间）
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.Response[[]model.SessionInfo]
+// @Failure 401 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/sessions [get]
+func ListSessions(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	currentSessionID := c.GetString("session_id")
+
+	db := database.GetDB()
+	var tokens []model.RefreshToken
+	if err := db.Where("user_id = ? AND revoked = false AND consumed_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+
+	sessions := make([]model.SessionInfo, 0, len(tokens))
+	for _, t := range tokens {
+		sessions = append(sessions, model.SessionInfo{
+			SessionID:  t.TokenID,
+			IPAddress:  t.IPAddress,
+			UserAgent:  t.UserAgent,
+			Platform:   t.Platform,
+			CreatedAt:  t.CreatedAt,
+			LastUsedAt: t.LastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+			Current:    t.FamilyID != "" && t.FamilyID == currentSessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, model.Response[[]model.SessionInfo]{Success: true, Code: 200, Data: sessions})
+}
+
+// RevokeSession godoc
+// @Summary 注销指定登录会话
+// @Description 撤销当前用户的某个登录会话，该设备需要重新登录
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param id path int true "会话ID"
+// @Success 200 {object} model.BaseResponse
+// @Failure 401 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/auth/sessions/{id} [delete]
+func RevokeSession(c *gin.Context) {
+	userID := c.GetInt("user_id")
+	sessionID, _ := strconv.Atoi(c.Param("id"))
+
+	db := database.GetDB()
+	var token model.RefreshToken
+	if err := db.Where("token_id = ? AND user_id = ?", sessionID, userID).First(&token).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "会话不存在", Code: 404})
+		return
+	}
+	if err := revokeSession(db, &token); err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	recordAuthEvent(c, db, userID, "", model.AuthEventSessionRevoke, true, fmt.Sprintf("session_id=%d", token.TokenID))
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, Code: 200})
+}
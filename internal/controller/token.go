@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute   // access token 有效期
+	refreshTokenTTL = 7 * 24 * time.Hour // refresh token 有效期
+)
+
+var (
+	errRefreshTokenInvalid = errors.New("refresh token invalid")
+	errRefreshTokenReused  = errors.New("refresh token reused")
+	errUserNotActive       = errors.New("user not active")
+)
+
+// RefreshClaims refresh token 的载荷，jti 保证同一秒内签发的 token 也互不相同
+type RefreshClaims struct {
+	UserID int `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// sessionMeta 登录会话的设备信息和认证方式
+type sessionMeta struct {
+	IPAddress string
+	UserAgent string
+	Platform  string
+	AMR       []string // 会话的认证方式，轮换 refresh token 时沿用
+}
+
+// newSessionMeta 从请求中提取客户端 IP、User-Agent 和 x-app-platform
+func newSessionMeta(c *gin.Context) sessionMeta {
+	return sessionMeta{
+		IPAddress: truncate(c.ClientIP(), 64),
+		UserAgent: truncate(c.GetHeader("User-Agent"), 512),
+		Platform:  truncate(c.GetHeader("X-App-Platform"), 32),
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// issueTokenPair 签发 access/refresh token，并保存 refresh token 的哈希。
+// familyID 为空时开启新的 token 家族（即一次新的登录会话）
+func issueTokenPair(db *gorm.DB, userID int, familyID string, meta sessionMeta) (accessToken string, refreshToken string, err error) {
+	if familyID == "" {
+		if familyID, err = generateSecureToken(); err != nil {
+			return "", "", err
+		}
+	}
+	var user model.User
+	if err := db.Select("user_id", "role", "status").First(&user, userID).Error; err != nil {
+		return "", "", err
+	}
+	// 未验证邮箱、已停用或已注销的账号不签发 token
+	if user.Status != "active" {
+		return "", "", errUserNotActive
+	}
+	accessToken, err = generateAccessToken(userID, user.Role, familyID, meta.AMR)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err = generateRefreshTokenJWT(userID)
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	if err := db.Create(&model.RefreshToken{
+		UserID:       userID,
+		RefreshToken: hashSecret(refreshToken),
+		FamilyID:     familyID,
+		ExpiresAt:    now.Add(refreshTokenTTL),
+		IPAddress:    meta.IPAddress,
+		UserAgent:    meta.UserAgent,
+		Platform:     meta.Platform,
+		AMR:          strings.Join(meta.AMR, ","),
+		LastUsedAt:   &now,
+	}).Error; err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// rotateRefreshToken 消费旧的 refresh token 并签发同一家族的新 token。
+// 已消费或已撤销的 token 再次出现视为被盗用，整个家族都会被撤销
+func rotateRefreshToken(db *gorm.DB, refreshTokenStr string, meta sessionMeta) (userID int, accessToken string, refreshToken string, err error) {
+	claims := &RefreshClaims{}
+	token, err := jwt.ParseWithClaims(refreshTokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return getRefreshSecret(), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, "", "", errRefreshTokenInvalid
+	}
+
+	var current model.RefreshToken
+	if err := db.Where("refresh_token = ?", hashSecret(refreshTokenStr)).First(&current).Error; err != nil {
+		return 0, "", "", errRefreshTokenInvalid
+	}
+	if current.UserID != claims.UserID {
+		return 0, "", "", errRefreshTokenInvalid
+	}
+	if current.ConsumedAt != nil || current.Revoked {
+		revokeTokenFamily(db, &current)
+		return current.UserID, "", "", errRefreshTokenReused
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return 0, "", "", errRefreshTokenInvalid
+	}
+
+	familyID := current.FamilyID
+	if current.AMR != "" {
+		meta.AMR = strings.Split(current.AMR, ",")
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 条件更新：并发请求中只有一个能消费成功，其余按重复使用处理
+		result := tx.Model(&model.RefreshToken{}).
+			Where("token_id = ? AND consumed_at IS NULL AND revoked = false", current.TokenID).
+			Update("consumed_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		if familyID == "" {
+			// 轮换上线前签发的旧 token 没有家族，从这里开始一个新家族
+			if familyID, err = generateSecureToken(); err != nil {
+				return err
+			}
+		}
+		accessToken, refreshToken, err = issueTokenPair(tx, current.UserID, familyID, meta)
+		if err != nil {
+			return err
+		}
+
+		var next model.RefreshToken
+		if err := tx.Where("refresh_token = ?", hashSecret(refreshToken)).First(&next).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.RefreshToken{}).
+			Where("token_id = ?", current.TokenID).
+			Update("replaced_by_id", next.TokenID).Error
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		revokeTokenFamily(db, &current)
+	}
+	if errors.Is(err, errUserNotActive) {
+		return current.UserID, "", "", errRefreshTokenInvalid
+	}
+	if err != nil {
+		return current.UserID, "", "", err
+	}
+	return current.UserID, accessToken, refreshToken, nil
+}
+
+// revokeTokenFamily 撤销 token 所在家族的全部 refresh token（重复使用检测时调用）
+func revokeTokenFamily(db *gorm.DB, token *model.RefreshToken) {
+	log.Printf("⚠️ 检测到 refresh token 重复使用，撤销会话 (user_id=%d, token_id=%d)", token.UserID, token.TokenID)
+	if err := revokeSession(db, token); err != nil {
+		log.Printf("撤销 refresh token 家族失败: %v", err)
+	}
+}
+
+// revokeSession 撤销 token 所在的会话：家族内的 refresh token 全部失效，
+// 该会话已签发的 access token 加入黑名单
+func revokeSession(db *gorm.DB, token *model.RefreshToken) error {
+	query := db.Model(&model.RefreshToken{}).Where("revoked = false")
+	if token.FamilyID != "" {
+		query = query.Where("family_id = ?", token.FamilyID)
+	} else {
+		query = query.Where("token_id = ?", token.TokenID)
+	}
+	if err := query.Update("revoked", true).Error; err != nil {
+		return err
+	}
+	if token.FamilyID != "" {
+		// 家族已撤销，不会再签发该会话的新 token，因此截止到条目过期前签发的全部拒绝，
+		// 撤销同一秒内签发的 access token 也能拦住
+		until := time.Now().Add(accessTokenTTL)
+		auth.AccessDenylist.Revoke(auth.SessionKey(token.FamilyID), until, until)
+	}
+	return nil
+}
+
+// revokeAllUserTokens 撤销用户的全部 refresh token，并使其已签发的 access token 失效
+func revokeAllUserTokens(db *gorm.DB, userID int) error {
+	if err := db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked = false", userID).
+		Update("revoked", true).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	auth.AccessDenylist.Revoke(auth.UserKey(userID), now, now.Add(accessTokenTTL))
+	return nil
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"ar-backend/internal/model"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB 连接 TEST_DATABASE_DSN 指定的 Postgres，未设置时跳过需要数据库的测试
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("未设置 TEST_DATABASE_DSN，跳过需要数据库的测试")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("连接测试数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.User{}, &model.RefreshToken{}); err != nil {
+		t.Fatalf("迁移测试数据库失败: %v", err)
+	}
+	return db
+}
+
+// createTestUser 创建一个已激活的用户，测试结束时连同其 refresh token 一并删除
+func createTestUser(t *testing.T, db *gorm.DB) model.User {
+	t.Helper()
+	user := model.User{
+		Email:    fmt.Sprintf("token-test-%d@example.com", time.Now().UnixNano()),
+		Provider: "email",
+		Status:   "active",
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("创建测试用户失败: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("user_id = ?", user.UserID).Delete(&model.RefreshToken{})
+		db.Delete(&model.User{}, user.UserID)
+	})
+	return user
+}
+
+func TestRotateRefreshTokenReuseDetection(t *testing.T) {
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+	db := openTestDB(t)
+
+	tests := []struct {
+		name string
+		// replay 在第一次轮换之后重放的 token：0 为最初的 token，1 为轮换得到的新 token
+		replay    int
+		wantErr   error
+		wantValid bool // 重放之后轮换得到的新 token 是否仍然可用
+	}{
+		{"重放已消费的 token 撤销整个家族", 0, errRefreshTokenReused, false},
+		{"正常使用新 token", 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUser(t, db)
+			_, first, err := issueTokenPair(db, user.UserID, "", sessionMeta{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, second, err := rotateRefreshToken(db, first, sessionMeta{})
+			if err != nil {
+				t.Fatalf("首次轮换失败: %v", err)
+			}
+
+			tokens := []string{first, second}
+			_, _, third, err := rotateRefreshToken(db, tokens[tt.replay], sessionMeta{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("重放 err = %v, want %v", err, tt.wantErr)
+			}
+
+			// 重复使用后整个家族被撤销，新 token 也不能再用
+			latest := second
+			if third != "" {
+				latest = third
+			}
+			_, _, _, err = rotateRefreshToken(db, latest, sessionMeta{})
+			if tt.wantValid && err != nil {
+				t.Errorf("最新 token 应仍可用: %v", err)
+			}
+			if !tt.wantValid && !errors.Is(err, errRefreshTokenReused) {
+				t.Errorf("最新 token err = %v, want %v", err, errRefreshTokenReused)
+			}
+
+			var active int64
+			db.Model(&model.RefreshToken{}).Where("user_id = ? AND revoked = false", user.UserID).Count(&active)
+			if !tt.wantValid && active != 0 {
+				t.Errorf("家族内仍有 %d 个未撤销的 token", active)
+			}
+		})
+	}
+}
+
+func TestRotateRefreshTokenConcurrentUse(t *testing.T) {
+	t.Setenv("JWT_REFRESH_SECRET", "test-refresh-secret")
+	db := openTestDB(t)
+	user := createTestUser(t, db)
+
+	_, refresh, err := issueTokenPair(db, user.UserID, "", sessionMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同一 token 并发轮换，只能有一个成功，其余按重复使用处理
+	const n = 5
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, _, _, err := rotateRefreshToken(db, refresh, sessionMeta{})
+			errs <- err
+		}()
+	}
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, errRefreshTokenReused):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded > 1 {
+		t.Errorf("%d 次并发轮换成功，最多应为 1 次", succeeded)
+	}
+}
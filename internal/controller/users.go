@@ -1,15 +1,18 @@
 package controller
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/model"
 	"ar-backend/pkg/database"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // CreateUser godoc
@@ -30,6 +33,15 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
+	if req.Password != "" {
+		hashedPwd, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "密码加密失败"})
+			return
+		}
+		req.Password = string(hashedPwd)
+	}
+
 	user := model.User{
 		Name:        req.Name,
 		NameKana:    req.NameKana,
@@ -55,13 +67,14 @@ func CreateUser(c *gin.Context) {
 
 // UpdateUser godoc
 // @Summary 更新用户
-// @Description 更新一个用户信息
+// @Description 更新一个用户信息。填写 password 时加密保存，并注销该用户的全部登录会话
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param user body model.UserReqEdit true "用户信息"
 // @Success 200 {object} model.BaseResponse
 // @Failure 400 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
 // @Failure 500 {object} model.BaseResponse
 // @Router /api/users [put]
 func UpdateUser(c *gin.Context) {
@@ -78,7 +91,32 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	db.Model(&user).Updates(req)
+	// 密码单独加密保存，修改后撤销该用户的全部登录会话
+	password := req.Password
+	req.Password = ""
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(req).Error; err != nil {
+			return err
+		}
+		if password == "" {
+			return nil
+		}
+		hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{"password": string(hashedPwd), "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		return revokeAllUserTokens(tx, user.UserID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
+		return
+	}
+	if password != "" {
+		recordAuthEvent(c, db, user.UserID, user.Email, model.AuthEventPasswordChange, true, fmt.Sprintf("by user_id=%d", c.GetInt("user_id")))
+	}
 	c.JSON(http.StatusOK, model.BaseResponse{Success: true})
 }
 
@@ -104,6 +142,83 @@ func DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, model.BaseResponse{Success: true})
 }
 
+// UpdateUserRole godoc
+// @Summary 修改用户角色
+// @Description 管理员修改用户角色，用户已签发的 access token 立即失效，刷新后获得新角色
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param user_id path int true "用户ID"
+// @Param payload body model.UserRoleReqEdit true "角色"
+// @Success 200 {object} model.Response[model.User]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/users/{user_id}/role [put]
+func UpdateUserRole(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("user_id"))
+	var req model.UserRoleReqEdit
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: err.Error()})
+		return
+	}
+	if !auth.IsValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "无效的角色: " + req.Role})
+		return
+	}
+	if userID == c.GetInt("user_id") {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "不能修改自己的角色"})
+		return
+	}
+
+	db := database.GetDB()
+	var user model.User
+	if err := db.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "用户不存在"})
+		return
+	}
+	if err := db.Model(&user).Updates(map[string]interface{}{"role": req.Role, "updated_at": time.Now()}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
+		return
+	}
+	now := time.Now()
+	auth.AccessDenylist.Revoke(auth.UserKey(user.UserID), now, now.Add(accessTokenTTL))
+
+	c.JSON(http.StatusOK, model.Response[model.User]{Success: true, Data: user})
+}
+
+// UnlockUserLogin godoc
+// @Summary 解除登录锁定
+// @Description 管理员清除用户因连续登录失败（密码、邮箱验证码、两步验证码）产生的等待和锁定。按来源 IP 的限制不会被清除，需等待其自然过期
+// @Tags Users
+// @Produce json
+// @Param user_id path int true "用户ID"
+// @Success 200 {object} model.BaseResponse
+// @Failure 404 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
+// @Router /api/users/{user_id}/unlock [post]
+func UnlockUserLogin(c *gin.Context) {
+	db := database.GetDB()
+	var user model.User
+	if err := db.First(&user, c.Param("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, model.BaseResponse{Success: false, ErrMessage: "用户不存在"})
+		return
+	}
+	// 按 IP 的计数由多个账号共享，不在此清除
+	store := auth.Logins().Store
+	for _, key := range []string{auth.AccountAttemptKey(user.Email), auth.MFAAttemptKey(user.UserID)} {
+		if err := store.Reset(key); err != nil {
+			c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error()})
+			return
+		}
+	}
+	recordAuthEvent(c, db, user.UserID, user.Email, model.AuthEventLoginUnlock, true, fmt.Sprintf("by user_id=%d", c.GetInt("user_id")))
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, Code: 200})
+}
+
 // GetUser godoc
 // @Summary 获取用户信息
 // @Description 获取单个用户信息
@@ -322,7 +437,7 @@ func InitializeSampleUsers(c *gin.Context) {
 			Avatar:           "https://via.placeholder.com/150/FF00FF/FFFFFF?text=孙七",
 			Provider:         "email",
 			Status:           "pending",
-			VerifyCode:       "1234",
+			VerifyCode:       "03ac674216f3e15c761ee1a5e255f067953623c8b388b4459e13f978d7c846f4", // 验证码 1234 的 SHA-256
 			VerifyCodeExpire: timePtr(time.Now().Add(10 * time.Minute)),
 			CreatedAt:        time.Now(),
 		},
@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+	"ar-backend/pkg/mailer"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	verifyCodeLength      = 6                // 验证码位数
+	verifyCodeTTL         = 10 * time.Minute // 验证码有效期
+	verifyResendInterval  = time.Minute      // 两次发送之间的最小间隔
+	verifyCodeMaxAttempts = 5                // 允许的最大尝试次数，重新发送不清零
+	verifyAttemptWindow   = time.Hour        // 距上次发送超过该时间后重新发送才会清零尝试次数
+)
+
+// generateNumericCode 使用 crypto/rand 生成指定位数的数字验证码
+func generateNumericCode(digits int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < digits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+// hashSecret 计算一次性凭证（验证码、令牌等）的 SHA-256 哈希，数据库只保存哈希值
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateSecureToken 生成 32 字节随机数的 URL 安全 base64 字符串，用于一次性令牌
+func generateSecureToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// verifyResendWait 返回距离允许再次发送验证码还需等待的时间
+func verifyResendWait(user *model.User) time.Duration {
+	if user.VerifyCodeSentAt == nil {
+		return 0
+	}
+	wait := time.Until(user.VerifyCodeSentAt.Add(verifyResendInterval))
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// verifyAttemptsExhausted 尝试次数已用完且距上次发送未满 verifyAttemptWindow 时返回需等待的时间
+func verifyAttemptsExhausted(user *model.User) time.Duration {
+	if user.VerifyAttempts < verifyCodeMaxAttempts || user.VerifyCodeSentAt == nil {
+		return 0
+	}
+	wait := time.Until(user.VerifyCodeSentAt.Add(verifyAttemptWindow))
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// issueVerifyCode 为用户生成新的验证码（只设置字段，不保存），返回明文验证码。
+// 尝试次数跨重发累计，距上次发送超过 verifyAttemptWindow 才清零，防止靠反复重发暴力猜测
+func issueVerifyCode(user *model.User) (string, error) {
+	if user.VerifyCodeSentAt == nil || time.Since(*user.VerifyCodeSentAt) > verifyAttemptWindow {
+		user.VerifyAttempts = 0
+	}
+	return issueUserCode(user, verifyCodeLength)
+}
+
+// issueUserCode 生成指定位数的验证码写入 verify_code 相关字段（只设置字段，不保存）
+func issueUserCode(user *model.User, digits int) (string, error) {
+	code, err := generateNumericCode(digits)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	expire := now.Add(verifyCodeTTL)
+	user.VerifyCode = hashSecret(code)
+	user.VerifyCodeExpire = &expire
+	user.VerifyCodeSentAt = &now
+	return code, nil
+}
+
+// consumeVerifyAttempt 以条件更新原子地占用一次尝试机会，次数已用完时返回 false
+func consumeVerifyAttempt(db *gorm.DB, userID int) (bool, error) {
+	result := db.Model(&model.User{}).
+		Where("user_id = ? AND verify_attempts < ?", userID, verifyCodeMaxAttempts).
+		UpdateColumn("verify_attempts", gorm.Expr("verify_attempts + 1"))
+	return result.RowsAffected == 1, result.Error
+}
+
+// sendVerifyCodeToEmail 发送邮箱验证码邮件
+func sendVerifyCodeToEmail(email string, code string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	return mailer.SendTemplate(ctx, mailer.Default(), email, "verify_email", map[string]any{
+		"Code":          code,
+		"ExpireMinutes": int(verifyCodeTTL.Minutes()),
+	})
+}
+
+// VerifyEmail godoc
+// @Summary 邮箱验证
+// @Description 使用邮件中的 6 位验证码激活待验证(pending)用户，激活后才能登录。同一账号最多尝试 5 次，重新发送验证码不会清零，距上次发送满 1 小时后重新发送才会清零
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.VerifyEmailRequest true "验证请求"
+// @Success 200 {object} model.Response[model.User]
+// @Failure 400 {object} model.BaseResponse
+// @Failure 429 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/verify [post]
+func VerifyEmail(c *gin.Context) {
+	var req model.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+
+	db := database.GetDB()
+	var user model.User
+	if err := db.Where("email = ?", strings.TrimSpace(req.Email)).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "验证码错误或已过期", Code: 400})
+		return
+	}
+	// 已激活的账号与不存在的邮箱返回相同错误，避免借此探测已注册的邮箱
+	if user.Status != "pending" || user.VerifyCode == "" || user.VerifyCodeExpire == nil || time.Now().After(*user.VerifyCodeExpire) {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "验证码错误或已过期", Code: 400})
+		return
+	}
+	// 先占用一次尝试机会再比较，并发请求也无法超过次数上限
+	ok, err := consumeVerifyAttempt(db, user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: err.Error(), Code: 500})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusTooManyRequests, model.BaseResponse{Success: false, ErrMessage: "尝试次数过多，请稍后重新获取验证码", Code: 429})
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(strings.TrimSpace(req.Code))), []byte(user.VerifyCode)) != 1 {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "验证码错误或已过期", Code: 400})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"status":             "active",
+		"verify_code":        "",
+		"verify_code_expire": nil,
+		"verify_attempts":    0,
+		"pending_password":   "",
+		"updated_at":         time.Now(),
+	}
+	// 注册时暂存的密码在邮箱验证通过后才生效，已有密码时不覆盖
+	if user.Password == "" && user.PendingPassword != "" {
+		updates["password"] = user.PendingPassword
+	}
+	// 以验证码为条件更新，同一验证码只能激活一次
+	result := db.Model(&model.User{}).
+		Where("user_id = ? AND verify_code = ?", user.UserID, user.VerifyCode).
+		Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "账号激活失败: " + result.Error.Error(), Code: 500})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "验证码错误或已过期", Code: 400})
+		return
+	}
+	user.Status = "active"
+
+	c.JSON(http.StatusOK, model.Response[model.User]{Success: true, Code: 200, Data: user})
+}
+
+// ResendVerifyCode godoc
+// @Summary 重新发送邮箱验证码
+// @Description 为待验证(pending)用户重新发送验证码，同一邮箱每分钟最多发送一次
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param payload body model.ResendVerifyCodeRequest true "重发请求"
+// @Success 200 {object} model.BaseResponse
+// @Failure 400 {object} model.BaseResponse
+// @Failure 429 {object} model.BaseResponse
+// @Failure 500 {object} model.BaseResponse
+// @Router /api/auth/verify/resend [post]
+func ResendVerifyCode(c *gin.Context) {
+	var req model.ResendVerifyCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "参数错误: " + err.Error(), Code: 400})
+		return
+	}
+
+	db := database.GetDB()
+	var user model.User
+	if err := db.Where("email = ? AND status = ?", strings.TrimSpace(req.Email), "pending").First(&user).Error; err != nil {
+		// 不暴露邮箱是否存在
+		c.JSON(http.StatusOK, model.BaseResponse{Success: true, ErrMessage: "如果该邮箱待验证，验证码已发送", Code: 200})
+		return
+	}
+
+	if wait := verifyResendWait(&user); wait > 0 {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, model.BaseResponse{Success: false, ErrMessage: "发送过于频繁，请稍后再试", Code: 429})
+		return
+	}
+	if wait := verifyAttemptsExhausted(&user); wait > 0 {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(wait.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, model.BaseResponse{Success: false, ErrMessage: "尝试次数过多，请稍后重新获取验证码", Code: 429})
+		return
+	}
+
+	code, err := issueVerifyCode(&user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "验证码生成失败", Code: 500})
+		return
+	}
+	if err := db.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "验证码更新失败: " + err.Error(), Code: 500})
+		return
+	}
+	if err := sendVerifyCodeToEmail(user.Email, code); err != nil {
+		log.Printf("发送验证码邮件失败 (%s): %v", user.Email, err)
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "验证码邮件发送失败", Code: 500})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.BaseResponse{Success: true, ErrMessage: "如果该邮箱待验证，验证码已发送", Code: 200})
+}
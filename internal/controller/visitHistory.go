@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"ar-backend/internal/auth"
+	"ar-backend/internal/middleware"
 	"ar-backend/internal/model"
 	"ar-backend/pkg/database"
 	"net/http"
@@ -11,14 +13,16 @@ import (
 
 // CreateVisitHistory godoc
 // @Summary 新建访问记录
-// @Description 新建一个访问记录
+// @Description 新建一个访问记录。登录用户只能为自己记录，user_id 取自 token；设施终端的 API Key 和拥有 visit_history:manage 权限的调用方需在 user_id 中指定用户
 // @Tags VisitHistories
 // @Accept json
 // @Produce json
 // @Param visit_history body model.VisitHistoryReqCreate true "访问记录信息"
 // @Success 200 {object} model.Response[model.VisitHistory]
 // @Failure 400 {object} model.BaseResponse
+// @Failure 403 {object} model.BaseResponse
 // @Failure 500 {object} model.BaseResponse
+// @Security ApiKeyAuth
 // @Router /api/visit_history [post]
 func CreateVisitHistory(c *gin.Context) {
 	var req model.VisitHistoryReqCreate
@@ -27,8 +31,18 @@ func CreateVisitHistory(c *gin.Context) {
 		return
 	}
 
+	userID, ok := visitHistoryUserID(c, req.UserID)
+	if !ok {
+		c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "只能为自己新建访问记录"})
+		return
+	}
+	if userID == 0 {
+		c.JSON(http.StatusBadRequest, model.BaseResponse{Success: false, ErrMessage: "缺少 user_id"})
+		return
+	}
+
 	history := model.VisitHistory{
-		UserID:     req.UserID,
+		UserID:     userID,
 		FacilityID: req.FacilityID,
 		ScanAt:     req.ScanAt,
 		IsActive:   req.IsActive,
@@ -41,6 +55,25 @@ func CreateVisitHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, model.Response[model.VisitHistory]{Success: true, Data: history})
 }
 
+// visitHistoryUserID 确定访问记录所属的用户：API Key（设施终端）和拥有 visit_history:manage 的调用方可以指定任意用户，
+// 省略时为调用方自己；普通用户只能为自己记录，指定其他用户时返回 false
+func visitHistoryUserID(c *gin.Context, requested int) (int, bool) {
+	p := middleware.CurrentPrincipal(c)
+	if p.IsAPIKey() || p.Can(auth.PermVisitHistoryManage) {
+		if requested != 0 {
+			return requested, true
+		}
+		return p.UserID, true
+	}
+	if p == nil {
+		return 0, false
+	}
+	if requested != 0 && requested != p.UserID {
+		return 0, false
+	}
+	return p.UserID, true
+}
+
 // UpdateVisitHistory godoc
 // @Summary 更新访问记录
 // @Description 更新访问记录信息
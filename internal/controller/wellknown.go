@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"net/http"
+
+	"ar-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS godoc
+// @Summary 获取 access token 验证公钥
+// @Description 以 JWKS 格式发布当前所有可用的验证公钥，其他服务按 token header 中的 kid 选择公钥验证
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} jwks.KeySet
+// @Router /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.Tokens().JWKS())
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"ar-backend/pkg/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader 设施终端和合作方系统携带 API Key 的请求头
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuth 校验 X-API-Key，并把调用方写入上下文的 "principal"
+func APIKeyAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(APIKeyHeader) == "" {
+			c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "缺少 API Key", Code: 401})
+			c.Abort()
+			return
+		}
+		if authenticateAPIKey(c) {
+			c.Next()
+		}
+	}
+}
+
+// APIKeyScope 用于匿名也可访问的读接口：携带 X-API-Key 时校验密钥并要求被授予指定的读权限，
+// 未携带时直接放行，匿名和登录用户的访问不受影响
+func APIKeyScope(perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(APIKeyHeader) == "" {
+			c.Next()
+			return
+		}
+		if !authenticateAPIKey(c) {
+			return
+		}
+		if !CurrentPrincipal(c).Can(perm) {
+			c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "API Key 没有权限: " + string(perm), Code: 403})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authenticateAPIKey 校验请求携带的 API Key 并写入调用方，失败时写入错误响应并返回 false
+func authenticateAPIKey(c *gin.Context) bool {
+	apiKey, err := auth.AuthenticateAPIKey(database.GetDB(), c.GetHeader(APIKeyHeader), c.ClientIP(), time.Now())
+	if errors.Is(err, auth.ErrAPIKeyExpired) {
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "API Key 已过期", Code: 401})
+		c.Abort()
+		return false
+	}
+	if errors.Is(err, auth.ErrAPIKeyInvalid) {
+		c.JSON(http.StatusUnauthorized, model.BaseResponse{Success: false, ErrMessage: "API Key 无效", Code: 401})
+		c.Abort()
+		return false
+	}
+	if err != nil {
+		log.Printf("API Key 校验失败: %v", err)
+		c.JSON(http.StatusInternalServerError, model.BaseResponse{Success: false, ErrMessage: "API Key 校验失败", Code: 500})
+		c.Abort()
+		return false
+	}
+
+	c.Set("principal", &auth.Principal{
+		Type:     auth.PrincipalAPIKey,
+		APIKeyID: apiKey.KeyID,
+		Scopes:   auth.APIKeyScopes(apiKey.Scopes),
+	})
+	c.Set("api_key_id", apiKey.KeyID)
+	return true
+}
+
+// Authenticate 携带 X-API-Key 时按 API Key 认证，否则按 access token 认证
+func Authenticate() gin.HandlerFunc {
+	jwtAuth := JWTAuth()
+	apiKeyAuth := APIKeyAuth()
+	return func(c *gin.Context) {
+		if c.GetHeader(APIKeyHeader) != "" {
+			apiKeyAuth(c)
+			return
+		}
+		jwtAuth(c)
+	}
+}
+
+// CurrentPrincipal 返回 JWTAuth 或 APIKeyAuth 写入的调用方，未认证时返回 nil
+func CurrentPrincipal(c *gin.Context) *auth.Principal {
+	if v, ok := c.Get("principal"); ok {
+		if p, ok := v.(*auth.Principal); ok {
+			return p
+		}
+	}
+	return nil
+}
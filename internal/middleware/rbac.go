@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"ar-backend/internal/auth"
+	"ar-backend/internal/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 检查当前调用方是否拥有指定权限，需放在 JWTAuth 或 Authenticate 之后。
+// API Key 按授予的权限判断；用户按角色判断，角色被 MFA_REQUIRED_ROLES 强制两步验证时，会话还必须完成两步验证
+func RequirePermission(perm auth.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p := CurrentPrincipal(c); p.IsAPIKey() {
+			if !p.Can(perm) {
+				c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "API Key 没有权限: " + string(perm), Code: 403})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		role := c.GetString("role")
+		if !auth.HasPermission(role, perm) {
+			c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "没有权限: " + string(perm), Code: 403})
+			c.Abort()
+			return
+		}
+		if auth.RoleRequiresMFA(role) && !auth.HasAMR(c.GetStringSlice("amr"), auth.AMRMFA) {
+			c.JSON(http.StatusForbidden, model.BaseResponse{Success: false, ErrMessage: "该操作需要先开启两步验证并使用验证码登录", Code: 403})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// 个人数据导出任务状态
+const (
+	DataExportPending   = "pending"
+	DataExportRunning   = "running"
+	DataExportCompleted = "completed"
+	DataExportFailed    = "failed"
+)
+
+// DataExport 表示数据库中的 data_exports 表，记录用户个人数据导出任务，导出文件保存在 DATA_EXPORT_DIR
+type DataExport struct {
+	ExportID    int        `gorm:"column:export_id;primaryKey" json:"export_id"`
+	UserID      int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	Status      string     `gorm:"column:status;type:varchar(16);not null" json:"status"`
+	FilePath    string     `gorm:"column:file_path;type:varchar(500)" json:"-"`
+	FileSize    int64      `gorm:"column:file_size;not null;default:0" json:"file_size"`
+	Error       string     `gorm:"column:error;type:varchar(255)" json:"error,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	CompletedAt *time.Time `gorm:"column:completed_at" json:"completed_at"`
+	ExpiresAt   *time.Time `gorm:"column:expires_at" json:"expires_at"` // 过期后导出文件被删除，需重新导出
+}
+
+// DeleteAccountRequest 注销账号请求
+type DeleteAccountRequest struct {
+	Confirm  string `json:"confirm" binding:"required"` // 需填写当前账号的邮箱
+	Password string `json:"password"`                   // 设置过密码的账号必填
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// APIKey 表示数据库中的 api_keys 表，供设施终端和合作方系统免登录调用 API。
+// 只保存密钥的 SHA-256 哈希，明文只在创建时返回一次
+type APIKey struct {
+	KeyID      int        `gorm:"column:key_id;primaryKey" json:"key_id"`
+	Name       string     `gorm:"column:name;type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"column:prefix;type:varchar(16);not null" json:"prefix"` // 密钥开头几位，用于辨认
+	KeyHash    string     `gorm:"column:key_hash;type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"column:scopes;type:varchar(512);not null" json:"scopes"` // 权限，逗号分隔，如 visit_history:write,stores:read
+	CreatedBy  int        `gorm:"column:created_by;not null" json:"created_by"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	LastUsedIP string     `gorm:"column:last_used_ip;type:varchar(64)" json:"last_used_ip"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// APIKeyReqCreate 创建 API Key 请求，expires_in_days 为 0 表示永不过期
+type APIKeyReqCreate struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"min=0"`
+}
+
+// APIKeyCreated 创建 API Key 的响应，key 为明文密钥，只返回这一次
+type APIKeyCreated struct {
+	APIKey APIKey `json:"api_key"`
+	Key    string `json:"key"`
+}
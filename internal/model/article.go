@@ -2,20 +2,42 @@ package model
 
 import "time"
 
+// 文章状态：draft → in_review → scheduled → published → archived
+const (
+	ArticleDraft     = "draft"     // 草稿，仅作者和编辑可见
+	ArticleInReview  = "in_review" // 已提交审核
+	ArticleScheduled = "scheduled" // 已审核，到 published_at 时自动发布
+	ArticlePublished = "published" // 已发布，公开可见
+	ArticleArchived  = "archived"  // 已下线
+)
+
 // Article 表示数据库中的 articles 表
 type Article struct {
 	ArticleID    int        `gorm:"column:article_id;primaryKey" json:"article_id"`
 	Title        string     `gorm:"column:title;type:varchar(255);not null" json:"title"`
 	BodyText     string     `gorm:"column:body_text;type:text;not null" json:"body_text"`
 	Category     string     `gorm:"column:category;type:varchar(100)" json:"category"`
-	LikeCount    int        `gorm:"column:like_count;not null" json:"like_count"`
+	LikeCount    int        `gorm:"column:like_count;not null" json:"like_count"` // 由点赞接口维护
 	ArticleImage []byte     `gorm:"column:article_image" json:"article_image,omitempty"`
 	ImageFileID  *int       `gorm:"column:image_file_id" json:"image_file_id,omitempty"`
-	CommentCount int        `gorm:"column:comment_count;not null" json:"comment_count"`
+	CommentCount int        `gorm:"column:comment_count;not null" json:"comment_count"` // 已发布的评论数，由评论接口维护
+	AuthorID     *int       `gorm:"column:author_id;index" json:"author_id"` // 作者，API Key 创建或早期文章为空
+	Status       string     `gorm:"column:status;type:varchar(16);not null;default:published;index" json:"status"` // 默认值用于存量文章补列，新建文章为 draft
+	PublishedAt  *time.Time `gorm:"column:published_at" json:"published_at"`
+	UnpublishAt  *time.Time `gorm:"column:unpublish_at" json:"unpublish_at"` // 到期后自动下线
 	CreatedAt    time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt    *time.Time `gorm:"column:updated_at" json:"updated_at"`
 	
-	ImageURL     string     `gorm:"-" json:"image_url,omitempty"`
+	ImageURL     string         `gorm:"-" json:"image_url,omitempty"`
+	Author       *AuthorSummary `gorm:"-" json:"author,omitempty"`
+	LikedByMe    *bool          `gorm:"-" json:"liked_by_me,omitempty"` // 携带 token 时返回当前用户是否已点赞
+}
+
+// AuthorSummary 文章响应中内嵌的作者信息
+type AuthorSummary struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar"`
 }
 
 // ArticleReqCreate 文章创建请求
@@ -23,10 +45,8 @@ type ArticleReqCreate struct {
 	Title        string `json:"title" binding:"required"`
 	BodyText     string `json:"body_text" binding:"required"`
 	Category     string `json:"category"`
-	LikeCount    int    `json:"like_count"`
 	ArticleImage []byte `json:"article_image"`
 	ImageFileID  *int   `json:"image_file_id"`
-	CommentCount int    `json:"comment_count"`
 }
 
 // ArticleReqEdit 文章更新请求
@@ -35,10 +55,8 @@ type ArticleReqEdit struct {
 	Title        string `json:"title"`
 	BodyText     string `json:"body_text"`
 	Category     string `json:"category"`
-	LikeCount    int    `json:"like_count"`
 	ArticleImage []byte `json:"article_image"`
 	ImageFileID  *int   `json:"image_file_id"`
-	CommentCount int    `json:"comment_count"`
 }
 
 // ArticleReqList 文章分页与搜索请求
@@ -46,6 +64,16 @@ type ArticleReqList struct {
 	Page     int    `json:"page" binding:"required"`
 	PageSize int    `json:"page_size" binding:"required"`
 	Keyword  string `json:"keyword"`
+	AuthorID int    `json:"author_id"` // 按作者筛选
+	Status   string `json:"status"`    // 按状态筛选，非 published 时只返回自己的文章（编辑可查看全部）
+}
+
+// ArticleStatusReqEdit 变更文章状态请求。
+// 改为 scheduled 时 publish_at 必填且须晚于当前时间；unpublish_at 可选，到期后自动下线
+type ArticleStatusReqEdit struct {
+	Status      string     `json:"status" binding:"required"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
 }
 
 // ArticleDetailRequest 获取单个文章请求
@@ -55,9 +83,7 @@ type ArticleDetailRequest struct {
 
 // ArticleCreateWithImageRequest 带图片上传的文章创建请求（multipart/form-data）
 type ArticleCreateWithImageRequest struct {
-	Title        string `form:"title" binding:"required"`
-	BodyText     string `form:"body_text" binding:"required"`
-	Category     string `form:"category"`
-	LikeCount    int    `form:"like_count"`
-	CommentCount int    `form:"comment_count"`
+	Title    string `form:"title" binding:"required"`
+	BodyText string `form:"body_text" binding:"required"`
+	Category string `form:"category"`
 }
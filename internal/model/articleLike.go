@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// ArticleLike 表示数据库中的 article_likes 表，同一用户对同一文章最多一条
+type ArticleLike struct {
+	ArticleID int       `gorm:"column:article_id;primaryKey;autoIncrement:false" json:"article_id"`
+	UserID    int       `gorm:"column:user_id;primaryKey;autoIncrement:false;index" json:"user_id"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// ArticleLikeState 点赞、取消点赞后的状态
+type ArticleLikeState struct {
+	ArticleID int  `json:"article_id"`
+	Liked     bool `json:"liked"`
+	LikeCount int  `json:"like_count"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"ar-backend/pkg/textdiff"
+)
+
+// ArticleRevision 表示数据库中的 article_revisions 表，文章每次创建、修改或恢复都保存一份完整快照，写入后不再修改
+type ArticleRevision struct {
+	RevisionID   int       `gorm:"column:revision_id;primaryKey" json:"revision_id"`
+	ArticleID    int       `gorm:"column:article_id;not null;uniqueIndex:idx_article_revision_no" json:"article_id"`
+	RevisionNo   int       `gorm:"column:revision_no;not null;uniqueIndex:idx_article_revision_no" json:"revision_no"` // 文章内从 1 开始递增
+	Title        string    `gorm:"column:title;type:varchar(255);not null" json:"title"`
+	BodyText     string    `gorm:"column:body_text;type:text;not null" json:"body_text,omitempty"`
+	Category     string    `gorm:"column:category;type:varchar(100)" json:"category"`
+	ImageFileID  *int      `gorm:"column:image_file_id" json:"image_file_id,omitempty"`
+	EditorID     *int      `gorm:"column:editor_id;index" json:"editor_id"`             // 修改人，API Key 修改或修订记录启用前的版本为空
+	RestoredFrom *int      `gorm:"column:restored_from" json:"restored_from,omitempty"` // 由哪个版本恢复而来
+	CreatedAt    time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// ArticleRevisionDiff 两个版本之间的逐行差异
+type ArticleRevisionDiff struct {
+	ArticleID    int             `json:"article_id"`
+	From         int             `json:"from"`
+	To           int             `json:"to"`
+	TitleChanged bool            `json:"title_changed"`
+	OldTitle     string          `json:"old_title"`
+	NewTitle     string          `json:"new_title"`
+	Inserted     int             `json:"inserted"` // 正文新增行数
+	Deleted      int             `json:"deleted"`  // 正文删除行数
+	Lines        []textdiff.Line `json:"lines"`
+}
@@ -23,20 +23,72 @@ type AuthResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	User         User   `json:"user"`
+	// 开启两步验证的账号第一步登录只返回 mfa_token，需调用 /api/auth/mfa/verify 完成登录
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+	// Cookie 会话模式下 token 写入 HttpOnly Cookie，写操作需在 X-CSRF-Token 请求头中携带该值
+	CSRFToken string `json:"csrf_token,omitempty"`
 }
 
+// RefreshTokenRequest 刷新请求，refresh_token 为空时读取 refresh_token Cookie
 type RefreshTokenRequest struct {
-	RefreshToken string `json:"refresh_token" binding:"required"`
+	RefreshToken string `json:"refresh_token"`
 }
 type RefreshTokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	CSRFToken    string `json:"csrf_token,omitempty"`
 }
 
 type RevokeTokenRequest struct {
-	RefreshToken string `json:"refresh_token" binding:"required"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type GoogleAuthRequest struct {
 	IdToken string `json:"id_token" binding:"required"`
 }
+
+// VerifyEmailRequest 邮箱验证请求
+type VerifyEmailRequest struct {
+	Email string `json:"email" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// ResendVerifyCodeRequest 重新发送验证码请求
+type ResendVerifyCodeRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// OTPRequest 申请邮箱登录验证码
+type OTPRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// OTPVerifyRequest 邮箱验证码登录请求
+type OTPVerifyRequest struct {
+	Email string `json:"email" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// AppleAuthRequest Sign in with Apple 登录请求。
+// user 仅在用户首次授权时由 Apple 返回给客户端，之后的登录不再包含
+type AppleAuthRequest struct {
+	IdToken string         `json:"id_token" binding:"required"`
+	Nonce   string         `json:"nonce"`
+	User    *AppleUserInfo `json:"user"`
+}
+
+// LineAuthRequest LINE Login 登录请求，nonce 为 LINE SDK 登录时使用的原始 nonce
+type LineAuthRequest struct {
+	IdToken string `json:"id_token" binding:"required"`
+	Nonce   string `json:"nonce"`
+}
+
+// AppleUserInfo Apple 首次授权时返回的用户信息
+type AppleUserInfo struct {
+	Name struct {
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
+	} `json:"name"`
+	Email string `json:"email"` // 未签名，仅供参考，以 token 中的邮箱为准
+}
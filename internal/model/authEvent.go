@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// 认证事件类型
+const (
+	AuthEventLogin           = "login"            // 登录成功
+	AuthEventLoginFailed     = "login_failed"     // 登录失败（密码、验证码错误或被锁定）
+	AuthEventMFAChallenge    = "mfa_challenge"    // 第一步登录成功，等待两步验证
+	AuthEventRegister        = "register"         // 注册
+	AuthEventTokenRefresh    = "token_refresh"    // 刷新 token
+	AuthEventTokenReuse      = "token_reuse"      // 已使用的 refresh token 被再次提交，会话被注销
+	AuthEventLogout          = "logout"           // 登出当前会话
+	AuthEventLogoutAll       = "logout_all"       // 登出全部设备
+	AuthEventSessionRevoke   = "session_revoke"   // 撤销指定会话
+	AuthEventIdentityLink    = "identity_link"    // 关联第三方登录
+	AuthEventIdentityUnlink  = "identity_unlink"  // 解除第三方登录关联
+	AuthEventPasswordReset   = "password_reset"   // 通过邮件重置密码
+	AuthEventPasswordChange  = "password_change"  // 管理员修改用户密码
+	AuthEventMFAEnable       = "mfa_enable"       // 开启两步验证
+	AuthEventMFADisable      = "mfa_disable"      // 关闭两步验证
+	AuthEventMFARecoveryCode = "mfa_recovery_new" // 重新生成恢复码
+	AuthEventLoginUnlock     = "login_unlock"     // 管理员解除登录锁定
+	AuthEventAccountDelete   = "account_delete"   // 用户注销账号
+)
+
+// AuthEvent 表示数据库中的 auth_events 表，记录登录、token、第三方关联、密码和两步验证相关操作
+type AuthEvent struct {
+	EventID   int64     `gorm:"column:event_id;primaryKey" json:"event_id"`
+	UserID    *int      `gorm:"column:user_id;index" json:"user_id"` // 邮箱不存在的登录失败等无法对应用户的事件为空
+	Email     string    `gorm:"column:email;type:varchar(320);index" json:"email"`
+	EventType string    `gorm:"column:event_type;type:varchar(32);not null;index" json:"event_type"`
+	Success   bool      `gorm:"column:success;not null" json:"success"`
+	Detail    string    `gorm:"column:detail;type:varchar(255)" json:"detail"` // 认证方式、提供方、失败原因等补充信息
+	IPAddress string    `gorm:"column:ip_address;type:varchar(64)" json:"ip_address"`
+	UserAgent string    `gorm:"column:user_agent;type:varchar(512)" json:"user_agent"`
+	Platform  string    `gorm:"column:platform;type:varchar(32)" json:"platform"`
+	CreatedAt time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
+
+// AuthEventReqList 认证事件分页查询，所有过滤条件均可选
+type AuthEventReqList struct {
+	Page      int        `json:"page" binding:"required,min=1"`
+	PageSize  int        `json:"page_size" binding:"required,min=1,max=200"`
+	UserID    int        `json:"user_id"`
+	Email     string     `json:"email"`
+	EventType string     `json:"event_type"`
+	Success   *bool      `json:"success"`
+	IPAddress string     `json:"ip_address"`
+	From      *time.Time `json:"from"`
+	To        *time.Time `json:"to"`
+}
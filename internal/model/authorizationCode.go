@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// AuthorizationCode 表示数据库中的 authorization_codes 表
+// OAuth 回调签发的一次性授权码，绑定 PKCE code_challenge，只保存哈希
+type AuthorizationCode struct {
+	CodeID        int        `gorm:"column:code_id;primaryKey" json:"code_id"`
+	CodeHash      string     `gorm:"column:code_hash;type:varchar(64);not null;uniqueIndex" json:"-"`
+	UserID        int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	ClientID      string     `gorm:"column:client_id;type:varchar(64)" json:"client_id"`
+	Provider      string     `gorm:"column:provider;type:varchar(32)" json:"provider"`
+	CodeChallenge string     `gorm:"column:code_challenge;type:varchar(128);not null" json:"-"`
+	RedirectURI   string     `gorm:"column:redirect_uri;type:varchar(1024)" json:"redirect_uri"`
+	ExpiresAt     time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	UsedAt        *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt     time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TokenExchangeRequest 用授权码换取 token 的请求
+type TokenExchangeRequest struct {
+	Code         string `json:"code" binding:"required"`
+	CodeVerifier string `json:"code_verifier" binding:"required"`
+	RedirectURI  string `json:"redirect_uri"`
+	ClientID     string `json:"client_id"`
+}
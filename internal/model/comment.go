@@ -5,19 +5,26 @@ import "time"
 // Comment 表示数据库中的 comments 表
 type Comment struct {
 	CommentID        int        `gorm:"column:comment_id;primaryKey" json:"comment_id"`
-	ArticleID        int        `gorm:"column:article_id;not null" json:"article_id"`
+	ArticleID        int        `gorm:"column:article_id;not null;index" json:"article_id"`
 	UserID           int        `gorm:"column:user_id;not null" json:"user_id"`
 	CommentText      string     `gorm:"column:comment_text;type:text;not null" json:"comment_text"`
 	CreatedAt        time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt        *time.Time `gorm:"column:updated_at" json:"updated_at"`
 	IsPublished      bool       `gorm:"column:is_published;not null" json:"is_published"`
-	ReplyToCommentID *int       `gorm:"column:reply_to_comment_id" json:"reply_to_comment_id"`
+	ReplyToCommentID *int       `gorm:"column:reply_to_comment_id;index" json:"reply_to_comment_id"`
+}
+
+// CommentThread 评论及其回复树
+type CommentThread struct {
+	Comment
+	ReplyCount     int64           `json:"reply_count"`      // 已发布的直接回复数
+	Replies        []CommentThread `json:"replies"`          // 按时间顺序的前若干条回复
+	HasMoreReplies bool            `json:"has_more_replies"` // 还有未返回的回复，可按 parent_id 分页获取
 }
 
 // CommentReqCreate 新建评论请求
 type CommentReqCreate struct {
 	ArticleID        int    `json:"article_id" binding:"required"`
-	UserID           int    `json:"user_id" binding:"required"`
 	CommentText      string `json:"comment_text" binding:"required"`
 	IsPublished      bool   `json:"is_published" binding:"required"`
 	ReplyToCommentID *int   `json:"reply_to_comment_id"`
@@ -33,9 +40,10 @@ type CommentReqEdit struct {
 
 // CommentReqList 评论分页与搜索请求
 type CommentReqList struct {
-	Page     int    `json:"page" binding:"required"`
-	PageSize int    `json:"page_size" binding:"required"`
-	Keyword  string `json:"keyword"`
+	ArticleID int    `json:"article_id" binding:"required"`
+	Page      int    `json:"page" binding:"required"`
+	PageSize  int    `json:"page_size" binding:"required"`
+	Keyword   string `json:"keyword"`
 }
 
 // CommentDetailRequest 获取单个评论请求
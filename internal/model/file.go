@@ -13,6 +13,7 @@ type File struct {
 	S3URL     string    `gorm:"column:s3_url;type:varchar(1000)" json:"s3_url,omitempty"`
 	Location  string    `gorm:"column:location;type:varchar(255);not null" json:"location"`
 	RelatedID int       `gorm:"column:related_id;not null" json:"related_id"`
+	UserID    *int      `gorm:"column:user_id;index" json:"user_id"` // 上传者，API Key 上传时为空
 	CreatedAt time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// LoginAttempt 表示数据库中的 login_attempts 表
+// 记录某个账号或 IP 的连续登录失败次数与锁定截止时间
+type LoginAttempt struct {
+	AttemptKey   string     `gorm:"column:attempt_key;type:varchar(320);primaryKey" json:"attempt_key"`
+	Failures     int        `gorm:"column:failures;not null;default:0" json:"failures"`
+	LastFailedAt time.Time  `gorm:"column:last_failed_at;not null" json:"last_failed_at"`
+	LockedUntil  *time.Time `gorm:"column:locked_until" json:"locked_until"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// LoginCode 表示数据库中的 login_codes 表，保存邮箱登录验证码。
+// 申请验证码时不创建用户，验证通过后才创建或激活用户
+type LoginCode struct {
+	Email     string    `gorm:"column:email;type:varchar(320);primaryKey" json:"email"`
+	CodeHash  string    `gorm:"column:code_hash;type:varchar(64);not null" json:"-"` // 验证码的 SHA-256 哈希
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index" json:"expires_at"`
+	SentAt    time.Time `gorm:"column:sent_at;not null" json:"sent_at"`
+	Attempts  int       `gorm:"column:attempts;not null;default:0" json:"-"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// UserMFA 表示数据库中的 user_mfa 表
+// 每个用户一条 TOTP 配置；密钥以 AES-GCM 加密保存，确认前 Enabled 为 false
+type UserMFA struct {
+	UserID       int        `gorm:"column:user_id;primaryKey;autoIncrement:false" json:"user_id"`
+	Secret       string     `gorm:"column:secret;type:varchar(255);not null" json:"-"`
+	Enabled      bool       `gorm:"column:enabled;not null;default:false" json:"enabled"`
+	LastUsedStep int64      `gorm:"column:last_used_step;not null;default:0" json:"-"` // 最近一次通过校验的时间步，防止重放
+	ConfirmedAt  *time.Time `gorm:"column:confirmed_at" json:"confirmed_at"`
+	CreatedAt    time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt    *time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (UserMFA) TableName() string { return "user_mfa" }
+
+// MFARecoveryCode 表示数据库中的 mfa_recovery_codes 表，只保存哈希，每个只能使用一次
+type MFARecoveryCode struct {
+	CodeID    int        `gorm:"column:code_id;primaryKey" json:"code_id"`
+	UserID    int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"column:code_hash;type:varchar(64);not null" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// MFAEnrollResponse 开始绑定验证器时返回的信息
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`      // 无法扫码时手动输入
+	OtpauthURI string `json:"otpauth_uri"` // otpauth://totp/...
+	QRCode     string `json:"qr_code"`     // PNG 二维码，data:image/png;base64,...
+}
+
+// MFACodeRequest 提交验证码（确认绑定、关闭两步验证、重新生成恢复码）
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFAVerifyRequest 登录第二步：提交挑战 token 和验证码（或恢复码）
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFARecoveryCodesResponse 新生成的恢复码，只在生成时返回一次
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatusResponse 当前用户的两步验证状态
+type MFAStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"` // 当前角色是否强制两步验证
+	ConfirmedAt            *time.Time `json:"confirmed_at"`
+	RemainingRecoveryCodes int64      `json:"remaining_recovery_codes"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// PasswordResetToken 表示数据库中的 password_reset_tokens 表
+type PasswordResetToken struct {
+	ResetID   int        `gorm:"column:reset_id;primaryKey" json:"reset_id"`
+	UserID    int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	TokenHash string     `gorm:"column:token_hash;type:varchar(64);not null;uniqueIndex" json:"-"` // 重置令牌的 SHA-256 哈希
+	ExpiresAt time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// ForgotPasswordRequest 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
@@ -3,13 +3,34 @@ package model
 import "time"
 
 // RefreshToken 表示数据库中的 refresh_tokens 表
+// 只保存 token 的哈希；每次刷新轮换出同一家族(FamilyID)的新 token，旧 token 标记为已消费
 type RefreshToken struct {
-	TokenID      int       `gorm:"column:token_id;primaryKey" json:"token_id"`
-	UserID       int       `gorm:"column:user_id;not null" json:"user_id"`
-	RefreshToken string    `gorm:"column:refresh_token;type:varchar(255);not null" json:"refresh_token"`
-	ExpiresAt    time.Time `gorm:"column:expires_at;not null" json:"expires_at"`
-	CreatedAt    time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
-	Revoked      bool      `gorm:"column:revoked;not null;default:false" json:"revoked"`
+	TokenID      int        `gorm:"column:token_id;primaryKey" json:"token_id"`
+	UserID       int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	RefreshToken string     `gorm:"column:refresh_token;type:varchar(255);not null;index" json:"refresh_token"` // token 的 SHA-256 哈希
+	FamilyID     string     `gorm:"column:family_id;type:varchar(64);index" json:"family_id"`
+	ExpiresAt    time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
+	CreatedAt    time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	ConsumedAt   *time.Time `gorm:"column:consumed_at" json:"consumed_at"`
+	ReplacedByID *int       `gorm:"column:replaced_by_id" json:"replaced_by_id"`
+	Revoked      bool       `gorm:"column:revoked;not null;default:false" json:"revoked"`
+	IPAddress    string     `gorm:"column:ip_address;type:varchar(64)" json:"ip_address"`
+	UserAgent    string     `gorm:"column:user_agent;type:varchar(512)" json:"user_agent"`
+	Platform     string     `gorm:"column:platform;type:varchar(32)" json:"platform"`
+	AMR          string     `gorm:"column:amr;type:varchar(64)" json:"amr"` // 会话的认证方式，逗号分隔，如 pwd,otp,mfa
+	LastUsedAt   *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+}
+
+// SessionInfo 当前用户的登录会话（对应一个未消费的 refresh token）
+type SessionInfo struct {
+	SessionID  int        `json:"session_id"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	Platform   string     `json:"platform"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"` // 是否为发起请求的会话
 }
 
 // RefreshTokenReqCreate 创建 Refresh Token 请求
@@ -12,14 +12,18 @@ type User struct {
 	Gender           *string    `gorm:"column:gender" json:"gender"`
 	PhoneNumber      string     `gorm:"column:phone_number" json:"phone_number"`
 	Email            string     `gorm:"column:email;not null;unique" json:"email"`
-	Password         string     `gorm:"column:password" json:"password"`
+	Password         string     `gorm:"column:password" json:"-"`         // bcrypt 哈希，不在任何响应中返回
+	PendingPassword  string     `gorm:"column:pending_password" json:"-"` // 待验证账号注册时设置的密码哈希，邮箱验证通过后才生效
 	Avatar           string     `gorm:"column:avatar" json:"avatar"`
 	GoogleID         string     `gorm:"column:google_id" json:"google_id"`
 	AppleID          string     `gorm:"column:apple_id" json:"apple_id"`
 	Provider         string     `gorm:"column:provider;not null" json:"provider"`
+	Role             string     `gorm:"column:role;type:varchar(32);not null;default:user" json:"role"`
 	Status           string     `gorm:"column:status;not null" json:"status"`
-	VerifyCode       string     `gorm:"column:verify_code" json:"verify_code"`
+	VerifyCode       string     `gorm:"column:verify_code" json:"-"` // 验证码的 SHA-256 哈希
 	VerifyCodeExpire *time.Time `gorm:"column:verify_code_expire" json:"verify_code_expire"`
+	VerifyCodeSentAt *time.Time `gorm:"column:verify_code_sent_at" json:"-"`
+	VerifyAttempts   int        `gorm:"column:verify_attempts;not null;default:0" json:"-"`
 	CreatedAt        time.Time  `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt        *time.Time `gorm:"column:updated_at" json:"updated_at"`
 }
@@ -57,6 +61,11 @@ type UserReqEdit struct {
 	Status      string `json:"status"`
 }
 
+// UserRoleReqEdit 修改用户角色请求
+type UserRoleReqEdit struct {
+	Role string `json:"role" binding:"required"` // admin / editor / store_owner / user
+}
+
 // UserReqList 用户分页与搜索请求
 type UserReqList struct {
 	Page     int    `json:"page" binding:"required"`
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// UserIdentity 表示数据库中的 user_identities 表
+// 一个用户可以关联多个第三方登录身份，(provider, subject) 全局唯一
+type UserIdentity struct {
+	IdentityID    int        `gorm:"column:identity_id;primaryKey" json:"identity_id"`
+	UserID        int        `gorm:"column:user_id;not null;index" json:"user_id"`
+	Provider      string     `gorm:"column:provider;type:varchar(32);not null;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject       string     `gorm:"column:subject;type:varchar(255);not null;uniqueIndex:idx_identity_provider_subject" json:"-"` // 第三方的用户ID (sub)
+	Email         string     `gorm:"column:email" json:"email"`
+	EmailVerified bool       `gorm:"column:email_verified;not null;default:false" json:"email_verified"`
+	LastLoginAt   *time.Time `gorm:"column:last_login_at" json:"last_login_at"`
+	CreatedAt     time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     *time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// LinkIdentityRequest 为当前用户关联第三方身份的请求
+type LinkIdentityRequest struct {
+	IdToken string `json:"id_token" binding:"required"`
+	Nonce   string `json:"nonce"`
+}
@@ -13,9 +13,9 @@ type VisitHistory struct {
 	UpdatedAt  *time.Time `gorm:"column:updated_at" json:"updated_at"`
 }
 
-// VisitHistoryReqCreate 新建访问记录请求
+// VisitHistoryReqCreate 新建访问记录请求，user_id 仅 API Key 和拥有 visit_history:manage 的调用方需要填写
 type VisitHistoryReqCreate struct {
-	UserID     int       `json:"user_id" binding:"required"`
+	UserID     int       `json:"user_id"`
 	FacilityID int       `json:"facility_id" binding:"required"`
 	ScanAt     time.Time `json:"scan_at" binding:"required"`
 	IsActive   bool      `json:"is_active" binding:"required"`
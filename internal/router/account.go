@@ -0,0 +1,24 @@
+package router
+
+import (
+	"ar-backend/internal/controller"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountRouter 当前用户的个人数据导出与账号注销
+type AccountRouter struct{}
+
+func (AccountRouter) Register(r *gin.RouterGroup) {
+	me := r.Group("/me")
+	{
+		authenticated(me, http.MethodGet, "/export", controller.ExportMyData)
+		authenticated(me, http.MethodGet, "/export/:export_id/download", controller.DownloadMyDataExport)
+		authenticated(me, http.MethodDelete, "", controller.DeleteMyAccount)
+	}
+}
+
+func init() {
+	Register(AccountRouter{})
+}
@@ -0,0 +1,26 @@
+package router
+
+import (
+	"ar-backend/internal/auth"
+	"ar-backend/internal/controller"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyRouter API Key 管理路由模块
+type APIKeyRouter struct{}
+
+// Register 注册 API Key 管理路由
+func (APIKeyRouter) Register(r *gin.RouterGroup) {
+	apiKeys := r.Group("/api-keys")
+	{
+		permit(apiKeys, auth.PermAPIKeysManage, http.MethodGet, "", controller.ListAPIKeys)
+		permit(apiKeys, auth.PermAPIKeysManage, http.MethodPost, "", controller.CreateAPIKey)
+		permit(apiKeys, auth.PermAPIKeysManage, http.MethodDelete, "/:key_id", controller.RevokeAPIKey)
+	}
+}
+
+func init() {
+	Register(APIKeyRouter{})
+}
@@ -1,8 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
-	"ar-backend/internal/middleware"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,22 +14,29 @@ type ArticleRouter struct{}
 // Register 注册文章路由
 func (ArticleRouter) Register(api *gin.RouterGroup) {
 	article := api.Group("/articles")
-	
-	// 公开访问的路由
-	api.GET("/articles/:article_id", controller.GetArticle)
-	api.POST("/articles/list", controller.ListArticles)
-	
-	// 带图片上传的文章创建（暂时不需要认证，方便测试）
-	article.POST("/with-image", controller.CreateArticleWithImage)
+
+	// 公开访问的路由，登录用户还可以看到自己未发布的文章
+	optionalAuth(article, http.MethodGet, "/:article_id", controller.GetArticle)
+	optionalAuth(article, http.MethodPost, "/list", controller.ListArticles)
+	optionalAuth(article, http.MethodGet, "/:article_id/comments", controller.ListArticleComments)
 
 	// 需要认证的路由
-	aritcleAuth := article.Group("/articles")
-	aritcleAuth.Use(middleware.JWTAuth())
-	{
-		article.POST("", controller.CreateArticle)
-		article.PUT("", controller.UpdateArticle)
-		article.DELETE("/:article_id", controller.DeleteArticle)
-	}
+	permit(article, auth.PermArticlesWrite, http.MethodPost, "/with-image", controller.CreateArticleWithImage)
+	permit(article, auth.PermArticlesWrite, http.MethodPost, "", controller.CreateArticle)
+	// 作者本人可以修改、删除自己的文章，拥有 articles:manage 的编辑、管理员可以处理任何文章
+	permit(article, auth.PermArticlesWrite, http.MethodPut, "", controller.UpdateArticle)
+	permit(article, auth.PermArticlesWrite, http.MethodDelete, "/:article_id", controller.DeleteArticle)
+	permit(article, auth.PermArticlesWrite, http.MethodPut, "/:article_id/status", controller.ChangeArticleStatus)
+
+	// 点赞需要登录，同一用户重复点赞、取消点赞不会重复计数
+	authenticated(article, http.MethodPut, "/:article_id/like", controller.LikeArticle)
+	authenticated(article, http.MethodDelete, "/:article_id/like", controller.UnlikeArticle)
+
+	// 修改记录，仅作者本人和编辑可查看、恢复
+	permit(article, auth.PermArticlesWrite, http.MethodGet, "/:article_id/revisions", controller.ListArticleRevisions)
+	permit(article, auth.PermArticlesWrite, http.MethodGet, "/:article_id/revisions/diff", controller.DiffArticleRevisions)
+	permit(article, auth.PermArticlesWrite, http.MethodGet, "/:article_id/revisions/:revision_no", controller.GetArticleRevision)
+	permit(article, auth.PermArticlesWrite, http.MethodPost, "/:article_id/revisions/:revision_no/restore", controller.RestoreArticleRevision)
 }
 
 func init() {
@@ -0,0 +1,24 @@
+package router
+
+import (
+	"ar-backend/internal/auth"
+	"ar-backend/internal/controller"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthEventRouter 认证事件查询路由模块
+type AuthEventRouter struct{}
+
+// Register 注册认证事件查询路由
+func (AuthEventRouter) Register(r *gin.RouterGroup) {
+	authEvents := r.Group("/auth-events")
+	{
+		permit(authEvents, auth.PermAuthEventsRead, http.MethodPost, "/list", controller.ListAuthEvents)
+	}
+}
+
+func init() {
+	Register(AuthEventRouter{})
+}
@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type CommentRouter struct{}
 func (CommentRouter) Register(r *gin.RouterGroup) {
 	comment := r.Group("/comments")
 	{
-		comment.POST("", controller.CreateComment)
-		comment.PUT("", controller.UpdateComment)
-		comment.DELETE(":comment_id", controller.DeleteComment)
-		comment.GET(":comment_id", controller.GetComment)
-		comment.POST("/list", controller.ListComments)
+		permit(comment, auth.PermCommentsWrite, http.MethodPost, "", controller.CreateComment)
+		permit(comment, auth.PermCommentsWrite, http.MethodPut, "", controller.UpdateComment)               // 作者本人或 comments:manage
+		permit(comment, auth.PermCommentsWrite, http.MethodDelete, ":comment_id", controller.DeleteComment) // 作者本人或 comments:manage
+		optionalAuth(comment, http.MethodGet, ":comment_id", controller.GetComment)
+		optionalAuth(comment, http.MethodPost, "/list", controller.ListComments)
 	}
 }
 
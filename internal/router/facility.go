@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type FacilityRouter struct{}
 func (FacilityRouter) Register(r *gin.RouterGroup) {
 	facility := r.Group("/facilities")
 	{
-		facility.POST("", controller.CreateFacility)
-		facility.PUT(":id", controller.UpdateFacility)
-		facility.DELETE(":id", controller.DeleteFacility)
-		facility.GET(":id", controller.GetFacility)
-		facility.POST("/list", controller.ListFacilities)
+		permit(facility, auth.PermFacilitiesWrite, http.MethodPost, "", controller.CreateFacility)
+		permit(facility, auth.PermFacilitiesWrite, http.MethodPut, ":id", controller.UpdateFacility)
+		permit(facility, auth.PermFacilitiesWrite, http.MethodDelete, ":id", controller.DeleteFacility)
+		publicRead(facility, auth.PermFacilitiesRead, http.MethodGet, ":id", controller.GetFacility)
+		publicRead(facility, auth.PermFacilitiesRead, http.MethodPost, "/list", controller.ListFacilities)
 	}
 }
 
@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,20 +16,20 @@ func (FileRouter) Register(r *gin.RouterGroup) {
 	file := r.Group("/files")
 	{
 		// 文件上传（multipart/form-data）
-		file.POST("/upload", controller.UploadFile)
-		
+		permit(file, auth.PermFilesWrite, http.MethodPost, "/upload", controller.UploadFile)
+
 		// 文件下载
-		file.GET("/:file_id/download", controller.DownloadFile)
-		
+		public(file, http.MethodGet, "/:file_id/download", controller.DownloadFile)
+
 		// S3 连接测试
-		file.GET("/test-s3", controller.TestS3Connection)
-		
+		permit(file, auth.PermSystemManage, http.MethodGet, "/test-s3", controller.TestS3Connection)
+
 		// 现有的 API
-		file.POST("", controller.CreateFile)
-		file.PUT("", controller.UpdateFile)
-		file.DELETE("/:file_id", controller.DeleteFile)
-		file.GET("/:file_id", controller.GetFile)
-		file.POST("/list", controller.ListFiles)
+		permit(file, auth.PermFilesWrite, http.MethodPost, "", controller.CreateFile)
+		permit(file, auth.PermFilesManage, http.MethodPut, "", controller.UpdateFile)
+		permit(file, auth.PermFilesManage, http.MethodDelete, "/:file_id", controller.DeleteFile)
+		public(file, http.MethodGet, "/:file_id", controller.GetFile)
+		public(file, http.MethodPost, "/list", controller.ListFiles)
 	}
 }
 
@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type LanguageRouter struct{}
 func (LanguageRouter) Register(r *gin.RouterGroup) {
 	languages := r.Group("/languages")
 	{
-		languages.POST("", controller.CreateLanguage)               // 新建语言
-		languages.PUT("", controller.UpdateLanguage)                // 更新语言
-		languages.DELETE(":language_id", controller.DeleteLanguage) // 删除语言
-		languages.GET(":language_id", controller.GetLanguage)       // 获取单个语言
-		languages.POST("/list", controller.ListLanguages)           // 获取语言分页列表
+		permit(languages, auth.PermLanguagesWrite, http.MethodPost, "", controller.CreateLanguage)               // 新建语言
+		permit(languages, auth.PermLanguagesWrite, http.MethodPut, "", controller.UpdateLanguage)                // 更新语言
+		permit(languages, auth.PermLanguagesWrite, http.MethodDelete, ":language_id", controller.DeleteLanguage) // 删除语言
+		publicRead(languages, auth.PermLanguagesRead, http.MethodGet, ":language_id", controller.GetLanguage)    // 获取单个语言
+		publicRead(languages, auth.PermLanguagesRead, http.MethodPost, "/list", controller.ListLanguages)        // 获取语言分页列表
 	}
 }
 
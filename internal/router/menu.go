@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type MenuRouter struct{}
 func (MenuRouter) Register(r *gin.RouterGroup) {
 	menu := r.Group("/menus")
 	{
-		menu.POST("", controller.CreateMenu)
-		menu.PUT("", controller.UpdateMenu)
-		menu.DELETE(":menu_id", controller.DeleteMenu)
-		menu.GET(":menu_id", controller.GetMenu)
-		menu.POST("/list", controller.ListMenus)
+		permit(menu, auth.PermMenusWrite, http.MethodPost, "", controller.CreateMenu)
+		permit(menu, auth.PermMenusWrite, http.MethodPut, "", controller.UpdateMenu)
+		permit(menu, auth.PermMenusWrite, http.MethodDelete, ":menu_id", controller.DeleteMenu)
+		publicRead(menu, auth.PermMenusRead, http.MethodGet, ":menu_id", controller.GetMenu)
+		publicRead(menu, auth.PermMenusRead, http.MethodPost, "/list", controller.ListMenus)
 	}
 }
 
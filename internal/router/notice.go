@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type NoticeRouter struct{}
 func (NoticeRouter) Register(r *gin.RouterGroup) {
 	notice := r.Group("/notices")
 	{
-		notice.POST("", controller.CreateNotice)
-		notice.PUT("", controller.UpdateNotice)
-		notice.DELETE(":notice_id", controller.DeleteNotice)
-		notice.GET(":notice_id", controller.GetNotice)
-		notice.POST("/list", controller.ListNotices)
+		permit(notice, auth.PermNoticesWrite, http.MethodPost, "", controller.CreateNotice)
+		permit(notice, auth.PermNoticesWrite, http.MethodPut, "", controller.UpdateNotice)
+		permit(notice, auth.PermNoticesWrite, http.MethodDelete, ":notice_id", controller.DeleteNotice)
+		publicRead(notice, auth.PermNoticesRead, http.MethodGet, ":notice_id", controller.GetNotice)
+		publicRead(notice, auth.PermNoticesRead, http.MethodPost, "/list", controller.ListNotices)
 	}
 }
 
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"ar-backend/internal/auth"
+	"ar-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	policyPublic        = "public"        // 无需登录
+	policyAuthenticated = "authenticated" // 登录即可
+)
+
+// routePolicies 记录每个路由声明的访问策略（"METHOD /path" → 权限），用于启动时检查
+var routePolicies = map[string]string{}
+
+// permit 注册需要指定权限的路由（自动加上认证），登录用户和被授予该权限的 API Key 均可访问
+func permit(rg *gin.RouterGroup, perm auth.Permission, method string, relativePath string, handlers ...gin.HandlerFunc) {
+	chain := append([]gin.HandlerFunc{middleware.Authenticate(), middleware.RequirePermission(perm)}, handlers...)
+	handle(rg, string(perm), method, relativePath, chain)
+}
+
+// authenticated 注册登录即可访问的路由（自动加上 JWTAuth）
+func authenticated(rg *gin.RouterGroup, method string, relativePath string, handlers ...gin.HandlerFunc) {
+	chain := append([]gin.HandlerFunc{middleware.JWTAuth()}, handlers...)
+	handle(rg, policyAuthenticated, method, relativePath, chain)
+}
+
+// public 注册公开路由，写操作也必须通过它显式声明为公开
+func public(rg *gin.RouterGroup, method string, relativePath string, handlers ...gin.HandlerFunc) {
+	handle(rg, policyPublic, method, relativePath, handlers)
+}
+
+// publicRead 注册匿名可读的公开路由，携带 X-API-Key 调用时要求 API Key 被授予指定的读权限
+func publicRead(rg *gin.RouterGroup, perm auth.Permission, method string, relativePath string, handlers ...gin.HandlerFunc) {
+	chain := append([]gin.HandlerFunc{middleware.APIKeyScope(perm)}, handlers...)
+	handle(rg, policyPublic, method, relativePath, chain)
+}
+
+// optionalAuth 注册公开路由，携带有效 access token 时识别当前用户（如作者查看自己的草稿）
+func optionalAuth(rg *gin.RouterGroup, method string, relativePath string, handlers ...gin.HandlerFunc) {
+	chain := append([]gin.HandlerFunc{middleware.OptionalAuth()}, handlers...)
+	handle(rg, policyPublic, method, relativePath, chain)
+}
+
+func handle(rg *gin.RouterGroup, policy string, method string, relativePath string, handlers []gin.HandlerFunc) {
+	rg.Handle(method, relativePath, handlers...)
+	routePolicies[method+" "+joinPaths(rg.BasePath(), relativePath)] = policy
+}
+
+// joinPaths 与 gin 内部拼接路由路径的规则一致
+func joinPaths(absolutePath string, relativePath string) string {
+	if relativePath == "" {
+		return absolutePath
+	}
+	finalPath := path.Join(absolutePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		return finalPath + "/"
+	}
+	return finalPath
+}
+
+// isMutatingMethod 判断是否为写操作
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// CheckRoutePermissions 检查所有写操作路由都已通过 permit / authenticated / public 声明访问策略
+func CheckRoutePermissions(routes gin.RoutesInfo) error {
+	var missing []string
+	for _, route := range routes {
+		if !isMutatingMethod(route.Method) {
+			continue
+		}
+		if _, ok := routePolicies[route.Method+" "+route.Path]; !ok {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("以下写操作路由未声明权限:\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type RefreshTokenRouter struct{}
 func (RefreshTokenRouter) Register(r *gin.RouterGroup) {
 	refreshToken := r.Group("/refresh_tokens")
 	{
-		refreshToken.POST("", controller.CreateRefreshToken)
-		refreshToken.PUT("", controller.UpdateRefreshToken)
-		refreshToken.DELETE(":token_id", controller.DeleteRefreshToken)
-		refreshToken.GET(":token_id", controller.GetRefreshToken)
-		refreshToken.POST("/list", controller.ListRefreshTokens)
+		permit(refreshToken, auth.PermTokensManage, http.MethodPost, "", controller.CreateRefreshToken)
+		permit(refreshToken, auth.PermTokensManage, http.MethodPut, "", controller.UpdateRefreshToken)
+		permit(refreshToken, auth.PermTokensManage, http.MethodDelete, ":token_id", controller.DeleteRefreshToken)
+		permit(refreshToken, auth.PermTokensManage, http.MethodGet, ":token_id", controller.GetRefreshToken)
+		permit(refreshToken, auth.PermTokensManage, http.MethodPost, "/list", controller.ListRefreshTokens)
 	}
 }
 
@@ -2,7 +2,7 @@ package router
 
 import (
 	"ar-backend/internal/controller"
-	"ar-backend/internal/middleware"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,23 +24,48 @@ func InitRouter() *gin.Engine {
 	r := gin.Default()
 	api := r.Group("/api")
 
+	// access token 验证公钥
+	wellKnown := r.Group("/.well-known")
+	public(wellKnown, http.MethodGet, "/jwks.json", controller.JWKS)
+
 	// 公开的认证路由 (不需要JWT验证)
 	authPublic := api.Group("/auth")
 	{
-		authPublic.POST("/login", controller.Login)
-		authPublic.POST("/register", controller.Register)
-		authPublic.POST("/refresh", controller.RefreshToken)
-		authPublic.POST("/logout", controller.RevokeRefreshToken)
-		authPublic.POST("/google", controller.GoogleAuth)
-		authPublic.GET("/google", controller.BeginGoogleAuth)
-		authPublic.GET("/google/callback", controller.GoogleAuthCallback)
+		public(authPublic, http.MethodPost, "/login", controller.Login)
+		public(authPublic, http.MethodPost, "/register", controller.Register)
+		public(authPublic, http.MethodPost, "/verify", controller.VerifyEmail)
+		public(authPublic, http.MethodPost, "/verify/resend", controller.ResendVerifyCode)
+		public(authPublic, http.MethodPost, "/otp/request", controller.RequestLoginCode)
+		public(authPublic, http.MethodPost, "/otp/verify", controller.VerifyLoginCode)
+		public(authPublic, http.MethodPost, "/password/forgot", controller.ForgotPassword)
+		public(authPublic, http.MethodPost, "/password/reset", controller.ResetPassword)
+		public(authPublic, http.MethodPost, "/refresh", controller.RefreshToken)
+		public(authPublic, http.MethodPost, "/logout", controller.RevokeRefreshToken)
+		public(authPublic, http.MethodPost, "/google", controller.GoogleAuth)
+		public(authPublic, http.MethodPost, "/apple", controller.AppleAuth)
+		public(authPublic, http.MethodPost, "/line", controller.LineAuth)
+		public(authPublic, http.MethodPost, "/token", controller.ExchangeToken)
+		public(authPublic, http.MethodPost, "/mfa/verify", controller.VerifyMFA)
+		public(authPublic, http.MethodGet, "/:provider", controller.BeginOAuth)
+		public(authPublic, http.MethodGet, "/:provider/callback", controller.OAuthCallback)
 	}
 
 	// 需要认证的用户路由
 	authProtected := api.Group("/auth")
-	authProtected.Use(middleware.JWTAuth())
 	{
-		authProtected.GET("/user/profile", controller.UserProfile)
+		authenticated(authProtected, http.MethodGet, "/user/profile", controller.UserProfile)
+		authenticated(authProtected, http.MethodPost, "/logout-all", controller.LogoutAll)
+		authenticated(authProtected, http.MethodGet, "/sessions", controller.ListSessions)
+		authenticated(authProtected, http.MethodGet, "/events", controller.ListMyAuthEvents)
+		authenticated(authProtected, http.MethodDelete, "/sessions/:id", controller.RevokeSession)
+		authenticated(authProtected, http.MethodGet, "/identities", controller.ListIdentities)
+		authenticated(authProtected, http.MethodPost, "/identities/:provider", controller.LinkIdentity)
+		authenticated(authProtected, http.MethodDelete, "/identities/:id", controller.UnlinkIdentity)
+		authenticated(authProtected, http.MethodGet, "/mfa", controller.GetMFAStatus)
+		authenticated(authProtected, http.MethodPost, "/mfa/enroll", controller.EnrollMFA)
+		authenticated(authProtected, http.MethodPost, "/mfa/confirm", controller.ConfirmMFA)
+		authenticated(authProtected, http.MethodPost, "/mfa/recovery-codes", controller.RegenerateRecoveryCodes)
+		authenticated(authProtected, http.MethodPost, "/mfa/disable", controller.DisableMFA)
 	}
 
 	// 注册所有模块路由
@@ -48,5 +73,10 @@ func InitRouter() *gin.Engine {
 		rr.Register(api)
 	}
 
+	// 所有写操作路由都必须声明权限，遗漏时启动即失败
+	if err := CheckRoutePermissions(r.Routes()); err != nil {
+		panic(err)
+	}
+
 	return r
 }
@@ -0,0 +1,89 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ar-backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestInitRouterDeclaresAllMutatingRoutes(t *testing.T) {
+	r := InitRouter()
+	if err := CheckRoutePermissions(r.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRoutePermissions(t *testing.T) {
+	noop := func(c *gin.Context) {}
+
+	tests := []struct {
+		name     string
+		register func(rg *gin.RouterGroup)
+		missing  string // 期望报告缺失的路由，空表示应通过
+	}{
+		{
+			name:     "未声明的 POST",
+			register: func(rg *gin.RouterGroup) { rg.POST("/unguarded", noop) },
+			missing:  "POST /test/unguarded",
+		},
+		{
+			name:     "未声明的 DELETE",
+			register: func(rg *gin.RouterGroup) { rg.DELETE("/unguarded/:id", noop) },
+			missing:  "DELETE /test/unguarded/:id",
+		},
+		{
+			name:     "未声明的 GET 不检查",
+			register: func(rg *gin.RouterGroup) { rg.GET("/read", noop) },
+		},
+		{
+			name:     "显式声明为公开的 POST",
+			register: func(rg *gin.RouterGroup) { public(rg, http.MethodPost, "/public", noop) },
+		},
+		{
+			name:     "登录即可访问的 PUT",
+			register: func(rg *gin.RouterGroup) { authenticated(rg, http.MethodPut, "/me", noop) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			tt.register(r.Group("/test"))
+			err := CheckRoutePermissions(r.Routes())
+			if tt.missing == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected %s to be reported", tt.missing)
+			}
+			if !strings.Contains(err.Error(), tt.missing) {
+				t.Fatalf("error %q does not mention %s", err, tt.missing)
+			}
+		})
+	}
+}
+
+func TestPublicReadAllowsAnonymous(t *testing.T) {
+	r := gin.New()
+	publicRead(r.Group("/test"), auth.PermStoresRead, http.MethodGet, "/stores/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// 未携带 X-API-Key 的匿名请求不检查读权限
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test/stores/1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
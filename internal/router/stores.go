@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type StoreRouter struct{}
 func (StoreRouter) Register(r *gin.RouterGroup) {
 	Store := r.Group("/stores")
 	{
-		Store.POST("", controller.CreateStore)
-		Store.PUT("", controller.UpdateStore)
-		Store.DELETE(":token_id", controller.DeleteStore)
-		Store.GET(":token_id", controller.GetStore) //
-		Store.POST("/list", controller.ListStores)
+		permit(Store, auth.PermStoresWrite, http.MethodPost, "", controller.CreateStore)
+		permit(Store, auth.PermStoresWrite, http.MethodPut, "", controller.UpdateStore)
+		permit(Store, auth.PermStoresWrite, http.MethodDelete, ":token_id", controller.DeleteStore)
+		publicRead(Store, auth.PermStoresRead, http.MethodGet, ":token_id", controller.GetStore) //
+		publicRead(Store, auth.PermStoresRead, http.MethodPost, "/list", controller.ListStores)
 		// Store.GET(":store_id/tags", controller.GetTagsByStore)
 		// Store.POST(":store_id/tags", controller.AddTagToStore) //
 		// Store.DELETE(":store_id/tags/:tag_id", controller.RemoveTagFromStore)
@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type TagRouter struct{}
 func (TagRouter) Register(r *gin.RouterGroup) {
 	tags := r.Group("/tags")
 	{
-		tags.POST("", controller.CreateTag)          // 新建标签
-		tags.PUT("", controller.UpdateTag)           // 更新标签
-		tags.DELETE(":tag_id", controller.DeleteTag) // 删除标签
-		tags.GET(":tag_id", controller.GetTag)       // 获取单个标签
-		tags.POST("/list", controller.ListTags)      // 标签分页列表
+		permit(tags, auth.PermTagsWrite, http.MethodPost, "", controller.CreateTag)          // 新建标签
+		permit(tags, auth.PermTagsWrite, http.MethodPut, "", controller.UpdateTag)           // 更新标签
+		permit(tags, auth.PermTagsWrite, http.MethodDelete, ":tag_id", controller.DeleteTag) // 删除标签
+		publicRead(tags, auth.PermTagsRead, http.MethodGet, ":tag_id", controller.GetTag)    // 获取单个标签
+		publicRead(tags, auth.PermTagsRead, http.MethodPost, "/list", controller.ListTags)   // 标签分页列表
 	}
 }
 
@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,13 +14,15 @@ type UserRouter struct{}
 func (UserRouter) Register(r *gin.RouterGroup) {
 	user := r.Group("/users")
 	{
-		user.POST("", controller.CreateUser)
-		user.PUT("", controller.UpdateUser)
-		user.DELETE("/:user_id", controller.DeleteUser)
-		user.GET("/:user_id", controller.GetUser)
-		user.POST("/list", controller.ListUsers)
-		user.GET("/statistics", controller.GetUserStatistics)
-		user.POST("/init-sample", controller.InitializeSampleUsers)
+		permit(user, auth.PermUsersWrite, http.MethodPost, "", controller.CreateUser)
+		permit(user, auth.PermUsersWrite, http.MethodPut, "", controller.UpdateUser)
+		permit(user, auth.PermUsersWrite, http.MethodDelete, "/:user_id", controller.DeleteUser)
+		permit(user, auth.PermUsersWrite, http.MethodPut, "/:user_id/role", controller.UpdateUserRole)
+		permit(user, auth.PermUsersWrite, http.MethodPost, "/:user_id/unlock", controller.UnlockUserLogin)
+		permit(user, auth.PermUsersRead, http.MethodGet, "/:user_id", controller.GetUser)
+		permit(user, auth.PermUsersRead, http.MethodPost, "/list", controller.ListUsers)
+		permit(user, auth.PermUsersRead, http.MethodGet, "/statistics", controller.GetUserStatistics)
+		permit(user, auth.PermSystemManage, http.MethodPost, "/init-sample", controller.InitializeSampleUsers)
 	}
 }
 
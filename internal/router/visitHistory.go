@@ -1,7 +1,9 @@
 package router
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/controller"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,11 +15,11 @@ type VisitHistoryRouter struct{}
 func (VisitHistoryRouter) Register(r *gin.RouterGroup) {
 	visitHistory := r.Group("/visit_history")
 	{
-		visitHistory.POST("", controller.CreateVisitHistory)
-		visitHistory.PUT("", controller.UpdateVisitHistory)
-		visitHistory.DELETE(":history_id", controller.DeleteVisitHistory)
-		visitHistory.GET(":history_id", controller.GetVisitHistory)
-		visitHistory.POST("/list", controller.ListVisitHistories)
+		permit(visitHistory, auth.PermVisitHistoryWrite, http.MethodPost, "", controller.CreateVisitHistory)
+		permit(visitHistory, auth.PermVisitHistoryManage, http.MethodPut, "", controller.UpdateVisitHistory)
+		permit(visitHistory, auth.PermVisitHistoryManage, http.MethodDelete, ":history_id", controller.DeleteVisitHistory)
+		permit(visitHistory, auth.PermVisitHistoryRead, http.MethodGet, ":history_id", controller.GetVisitHistory)
+		permit(visitHistory, auth.PermVisitHistoryRead, http.MethodPost, "/list", controller.ListVisitHistories)
 	}
 }
 
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"ar-backend/internal/auth"
 	"ar-backend/internal/model"
 	"ar-backend/pkg/database"
 	"fmt"
@@ -29,6 +30,9 @@ func InitializeAdminUser() {
 	err := db.Where("email = ?", adminEmail).First(&existingAdmin).Error
 	if err == nil {
 		fmt.Printf("管理员账户已存在: %s\n", adminEmail)
+		if existingAdmin.Role != auth.RoleAdmin {
+			db.Model(&existingAdmin).Update("role", auth.RoleAdmin)
+		}
 		return
 	}
 
@@ -40,6 +44,7 @@ func InitializeAdminUser() {
 		Email:       adminEmail,
 		Password:    hashedPassword,
 		Provider:    "email",
+		Role:        auth.RoleAdmin,
 		Status:      "active",
 		Address:     "系统管理",
 		PhoneNumber: "000-0000-0000",
@@ -147,7 +152,7 @@ func InitializeSampleUsers() {
 			Avatar:           "https://via.placeholder.com/150/FF00FF/FFFFFF?text=孙七",
 			Provider:         "email",
 			Status:           "pending",
-			VerifyCode:       "1234",
+			VerifyCode:       "03ac674216f3e15c761ee1a5e255f067953623c8b388b4459e13f978d7c846f4", // 验证码 1234 的 SHA-256
 			VerifyCodeExpire: timePtr(time.Now().Add(10 * time.Minute)),
 			CreatedAt:        time.Now(),
 		},
@@ -2,9 +2,11 @@ package main
 
 import (
 	"ar-backend/internal/auth"
+	"ar-backend/internal/controller"
 	"ar-backend/internal/model"
 	server "ar-backend/internal/service"
 	"ar-backend/pkg/database"
+	"ar-backend/pkg/mailer"
 	"fmt"
 	"log"
 	"net/http"
@@ -38,6 +40,9 @@ func main() {
 	// 自动迁移（AutoMigrate会自动创建不存在的表）
 	fmt.Println("🔄 正在进行数据库迁移...")
 	db := database.GetDB()
+	// 点赞表、评论的 article_id 索引不存在时说明是首次启用对应功能，迁移后需要校正一次计数
+	likesTableExisted := db.Migrator().HasTable(&model.ArticleLike{})
+	commentIndexExisted := db.Migrator().HasIndex(&model.Comment{}, "idx_comments_article_id")
 	db.AutoMigrate(
 		&model.Facility{},
 		&model.File{},
@@ -46,19 +51,66 @@ func main() {
 		&model.Language{},
 		&model.User{},
 		&model.RefreshToken{},
+		&model.PasswordResetToken{},
+		&model.LoginCode{},
+		&model.UserIdentity{},
+		&model.AuthorizationCode{},
+		&model.LoginAttempt{},
+		&model.UserMFA{},
+		&model.MFARecoveryCode{},
+		&model.APIKey{},
+		&model.AuthEvent{},
+		&model.DataExport{},
 		&model.Store{},
 		&model.Menu{},
 		&model.Article{},
+		&model.ArticleRevision{},
+		&model.ArticleLike{},
 		&model.Comment{},
 		&model.Tag{},
 		&model.Tagging{},
 	)
 	fmt.Println("✅ 数据库迁移完成")
 
+	// 把旧的 google_id / apple_id 迁移到 user_identities
+	if err := controller.BackfillUserIdentities(db); err != nil {
+		log.Printf("⚠️ 第三方登录身份迁移失败: %v", err)
+	}
+
+	// 点赞表首次创建时，把之前由客户端填写的 like_count 校正为实际点赞数
+	if !likesTableExisted {
+		if err := controller.RecountArticleLikes(db); err != nil {
+			log.Printf("⚠️ 文章点赞数校正失败: %v", err)
+		}
+	}
+	// 评论数改由服务端维护后首次启动时，把之前由客户端填写的 comment_count 校正为实际评论数
+	if !commentIndexExisted {
+		if err := controller.RecountArticleComments(db); err != nil {
+			log.Printf("⚠️ 文章评论数校正失败: %v", err)
+		}
+	}
+
+	// 多实例部署时登录失败计数需要共享，默认存数据库
+	if os.Getenv("LOGIN_ATTEMPT_STORE") != "memory" {
+		auth.LoginAttempts = auth.NewGormLoginAttemptStore(db)
+	}
+
+	// 定期清理超过保留期的认证事件
+	controller.StartAuthEventRetention(db)
+
+	// 中断的个人数据导出标记为失败，并定期删除过期的导出文件
+	controller.StartDataExportCleanup(db)
+
+	// 定时发布文章、到期下线
+	controller.StartArticleScheduler(db)
+
 	// 初始化示例用户数据
 	fmt.Println("👥 正在初始化用户数据...")
 	server.InitializeSampleUsers()
 
+	// 生产环境未配置 SMTP 时在启动阶段退出，不把验证码写进日志
+	mailer.Default()
+
 	// 初始化认证
 	fmt.Println("🔐 正在初始化认证模块...")
 	auth.NewAuth()
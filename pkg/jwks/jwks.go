@@ -0,0 +1,312 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JSONWebKey JWK 中用到的字段（RSA / EC / OKP）
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// KeySet JSON Web Key Set 结果
+type KeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// RemoteKeySet 从远程 URL 获取并缓存的公钥集合。
+// 缓存过期或遇到未知 kid 时重新拉取（两次拉取之间至少间隔 minRefetch，防止被恶意 kid 打满）；
+// 拉取失败后按指数退避延长间隔，远程不可用期间继续使用已缓存的公钥，验证请求不会每次都等待超时
+type RemoteKeySet struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefetch time.Duration
+	maxBackoff time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time // 最近一次成功拉取的时间
+	attemptedAt time.Time // 最近一次开始拉取的时间，无论成功与否
+	failures    int       // 连续拉取失败的次数
+	lastErr     error
+}
+
+// NewRemoteKeySet 创建远程公钥集合，ttl 为缓存有效期
+func NewRemoteKeySet(url string, ttl time.Duration) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:        url,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		ttl:        ttl,
+		minRefetch: 30 * time.Second,
+		maxBackoff: 5 * time.Minute,
+		keys:       map[string]interface{}{},
+	}
+}
+
+// retryDelay 距上次拉取至少需要间隔的时间：成功后为 minRefetch，连续失败时 minRefetch、2 倍、4 倍……直到 maxBackoff。调用方需持有 mu
+func (s *RemoteKeySet) retryDelay() time.Duration {
+	d := s.minRefetch
+	for i := 1; i < s.failures && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.maxBackoff {
+		return s.maxBackoff
+	}
+	return d
+}
+
+// Keyfunc 供 jwt.Parse 使用，根据 kid 返回公钥并校验签名算法与密钥类型匹配
+func (s *RemoteKeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token 缺少 kid")
+	}
+	key, err := s.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckSigningMethod(token.Method, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *RemoteKeySet) key(kid string) (interface{}, error) {
+	s.mu.Lock()
+	key, ok := s.keys[kid]
+	if ok && time.Since(s.fetchedAt) < s.ttl {
+		s.mu.Unlock()
+		return key, nil
+	}
+	// 在锁内登记本次拉取，并发的验证请求不会重复拉取
+	fetch := time.Since(s.attemptedAt) >= s.retryDelay()
+	if fetch {
+		s.attemptedAt = time.Now()
+	}
+	lastErr := s.lastErr
+	s.mu.Unlock()
+
+	if fetch {
+		if err := s.fetch(context.Background()); err != nil && !ok {
+			return nil, err
+		}
+		s.mu.Lock()
+		key, ok = s.keys[kid]
+		s.mu.Unlock()
+	}
+	if !ok {
+		if !fetch && lastErr != nil {
+			return nil, fmt.Errorf("未知的 kid: %s（最近一次拉取失败: %v）", kid, lastErr)
+		}
+		return nil, fmt.Errorf("未知的 kid: %s", kid)
+	}
+	return key, nil
+}
+
+// Refresh 立即从远程拉取公钥集合
+func (s *RemoteKeySet) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	s.attemptedAt = time.Now()
+	s.mu.Unlock()
+	return s.fetch(ctx)
+}
+
+// fetch 拉取公钥集合并记录结果，失败时保留已缓存的公钥
+func (s *RemoteKeySet) fetch(ctx context.Context) error {
+	keys, err := s.download(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failures++
+		s.lastErr = err
+		return err
+	}
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.failures = 0
+	s.lastErr = nil
+	return nil
+}
+
+// download 请求远程 JWKS 并解析
+func (s *RemoteKeySet) download(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取 JWKS 失败, status: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeySet(body)
+}
+
+// ParseKeySet 解析 JWKS JSON，返回 kid 到公钥的映射，不支持的密钥类型会被忽略
+func ParseKeySet(data []byte) (map[string]interface{}, error) {
+	var set KeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("解析 JWKS 失败: %v", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+// PublicKey 把 JWK 转换为 Go 公钥
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("无效的 Ed25519 公钥")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型: %s", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// CheckSigningMethod 防止算法混淆攻击：签名算法必须与公钥类型一致
+func CheckSigningMethod(method jwt.SigningMethod, key interface{}) error {
+	ok := false
+	switch key.(type) {
+	case *rsa.PublicKey:
+		_, ok = method.(*jwt.SigningMethodRSA)
+	case *ecdsa.PublicKey:
+		_, ok = method.(*jwt.SigningMethodECDSA)
+	case ed25519.PublicKey:
+		_, ok = method.(*jwt.SigningMethodEd25519)
+	}
+	if !ok {
+		return fmt.Errorf("签名算法 %s 与密钥类型不匹配", method.Alg())
+	}
+	return nil
+}
+
+// StartAutoRefresh 在后台按 interval 定期刷新公钥，ctx 取消时停止
+func (s *RemoteKeySet) StartAutoRefresh(ctx context.Context, interval time.Duration) {
+	go func() {
+		if err := s.Refresh(ctx); err != nil {
+			log.Printf("JWKS 预加载失败 (%s): %v", s.url, err)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("JWKS 刷新失败 (%s): %v", s.url, err)
+				}
+			}
+		}
+	}()
+}
+
+// NewJSONWebKey 把公钥编码为 JWK，用于对外发布 JWKS
+func NewJSONWebKey(kid string, alg string, pub interface{}) (JSONWebKey, error) {
+	key := JSONWebKey{Kid: kid, Alg: alg, Use: "sig"}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		key.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		key.Kty = "EC"
+		key.Crv = k.Curve.Params().Name
+		key.X = base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size)))
+		key.Y = base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		key.Kty = "OKP"
+		key.Crv = "Ed25519"
+		key.X = base64.RawURLEncoding.EncodeToString(k)
+	default:
+		return key, fmt.Errorf("不支持的公钥类型: %T", pub)
+	}
+	return key, nil
+}
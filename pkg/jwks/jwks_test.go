@@ -0,0 +1,237 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// testKeys 生成 RSA、EC、Ed25519 各一把测试密钥
+func testKeys(t *testing.T) (*rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey) {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rsaKey, ecKey, edKey
+}
+
+// jwksServer 返回发布指定公钥的测试 JWKS 服务，fail 为 true 时返回 500，hits 记录请求次数
+func jwksServer(t *testing.T, keys map[string]interface{}, fail *atomic.Bool, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	var set KeySet
+	for kid, pub := range keys {
+		k, err := NewJSONWebKey(kid, "", pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestJSONWebKeyRoundTrip(t *testing.T) {
+	rsaKey, ecKey, edKey := testKeys(t)
+	tests := []struct {
+		name string
+		pub  interface{}
+	}{
+		{"RSA", &rsaKey.PublicKey},
+		{"EC P-256", &ecKey.PublicKey},
+		{"Ed25519", edKey.Public()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := NewJSONWebKey("kid1", "", tt.pub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := json.Marshal(KeySet{Keys: []JSONWebKey{k}})
+			keys, err := ParseKeySet(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := keys["kid1"]
+			if !ok {
+				t.Fatal("kid1 missing after round trip")
+			}
+			if eq, ok := got.(interface{ Equal(crypto.PublicKey) bool }); !ok || !eq.Equal(tt.pub) {
+				t.Errorf("public key changed after round trip")
+			}
+		})
+	}
+}
+
+func TestParseKeySetSkipsUnusableKeys(t *testing.T) {
+	data := []byte(`{"keys":[
+		{"kty":"RSA","kid":"enc","use":"enc","n":"AQAB","e":"AQAB"},
+		{"kty":"EC","kid":"bad-curve","crv":"P-192","x":"AA","y":"AA"},
+		{"kty":"oct","kid":"symmetric","k":"c2VjcmV0"}
+	]}`)
+	keys, err := ParseKeySet(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("got %d keys, want 0", len(keys))
+	}
+	if _, err := ParseKeySet([]byte("not json")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestCheckSigningMethod(t *testing.T) {
+	rsaKey, ecKey, edKey := testKeys(t)
+	tests := []struct {
+		name    string
+		method  jwt.SigningMethod
+		key     interface{}
+		wantErr bool
+	}{
+		{"RS256 + RSA", jwt.SigningMethodRS256, &rsaKey.PublicKey, false},
+		{"RS384 + RSA", jwt.SigningMethodRS384, &rsaKey.PublicKey, false},
+		{"ES256 + EC", jwt.SigningMethodES256, &ecKey.PublicKey, false},
+		{"EdDSA + Ed25519", jwt.SigningMethodEdDSA, edKey.Public(), false},
+		{"HS256 + RSA（算法混淆）", jwt.SigningMethodHS256, &rsaKey.PublicKey, true},
+		{"ES256 + RSA", jwt.SigningMethodES256, &rsaKey.PublicKey, true},
+		{"RS256 + EC", jwt.SigningMethodRS256, &ecKey.PublicKey, true},
+		{"RS256 + Ed25519", jwt.SigningMethodRS256, edKey.Public(), true},
+		{"未知密钥类型", jwt.SigningMethodRS256, "secret", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSigningMethod(tt.method, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckSigningMethod(%s, %T) err = %v, wantErr %v", tt.method.Alg(), tt.key, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	s := NewRemoteKeySet("http://unused", time.Hour)
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{5, 5 * time.Minute},
+		{50, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		s.failures = tt.failures
+		if got := s.retryDelay(); got != tt.want {
+			t.Errorf("failures=%d: retryDelay = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestKeyBacksOffAfterFailure(t *testing.T) {
+	var fail atomic.Bool
+	var hits atomic.Int32
+	fail.Store(true)
+	srv := jwksServer(t, nil, &fail, &hits)
+
+	s := NewRemoteKeySet(srv.URL, time.Hour)
+	if _, err := s.key("kid1"); err == nil {
+		t.Fatal("expected error while JWKS is unavailable")
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("hits = %d, want 1", hits.Load())
+	}
+
+	// 退避期间不再请求远程，直接返回最近一次失败的原因
+	_, err := s.key("kid1")
+	if err == nil || !strings.Contains(err.Error(), "最近一次拉取失败") {
+		t.Fatalf("err = %v, want it to mention the last fetch failure", err)
+	}
+	if hits.Load() != 1 {
+		t.Fatalf("hits = %d during backoff, want 1", hits.Load())
+	}
+
+	// 退避时间过后重新拉取，连续失败时间隔翻倍
+	s.mu.Lock()
+	s.attemptedAt = time.Now().Add(-s.retryDelay())
+	s.mu.Unlock()
+	s.key("kid1")
+	if hits.Load() != 2 {
+		t.Fatalf("hits = %d after backoff elapsed, want 2", hits.Load())
+	}
+	s.mu.Lock()
+	failures, delay := s.failures, s.retryDelay()
+	s.mu.Unlock()
+	if failures != 2 || delay != time.Minute {
+		t.Errorf("failures = %d, retryDelay = %v, want 2 and 1m", failures, delay)
+	}
+}
+
+func TestKeyUsesCacheWhenRefreshFails(t *testing.T) {
+	rsaKey, _, _ := testKeys(t)
+	var fail atomic.Bool
+	var hits atomic.Int32
+	srv := jwksServer(t, map[string]interface{}{"kid1": &rsaKey.PublicKey}, &fail, &hits)
+
+	s := NewRemoteKeySet(srv.URL, time.Hour)
+	if err := s.Refresh(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 缓存过期后远程不可用，仍返回已缓存的公钥
+	fail.Store(true)
+	s.mu.Lock()
+	s.fetchedAt = time.Now().Add(-2 * time.Hour)
+	s.attemptedAt = time.Time{}
+	s.mu.Unlock()
+	key, err := s.key("kid1")
+	if err != nil {
+		t.Fatalf("expected cached key, got %v", err)
+	}
+	if key.(*rsa.PublicKey).N.Cmp(rsaKey.N) != 0 {
+		t.Error("returned key does not match")
+	}
+	if hits.Load() != 2 {
+		t.Errorf("hits = %d, want 2", hits.Load())
+	}
+
+	// 远程恢复后未知 kid 触发重新拉取，但受 minRefetch 限制
+	fail.Store(false)
+	if _, err := s.key("unknown"); err == nil {
+		t.Error("expected error for unknown kid")
+	}
+	if hits.Load() != 2 {
+		t.Errorf("hits = %d, unknown kid should not refetch within backoff", hits.Load())
+	}
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogMailer 不真正发送邮件，而是把邮件内容写入文件或标准日志，用于本地开发和测试
+type LogMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogMailer 创建 LogMailer，path 为空时输出到标准日志
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{path: path}
+}
+
+// Send 记录邮件内容
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	entry := fmt.Sprintf("=== %s ===\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), strings.Join(msg.To, ", "), msg.Subject, msg.TextBody)
+
+	if m.path == "" {
+		log.Printf("📧 [LogMailer]\n%s", entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("打开邮件日志文件失败: %v", err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(entry)
+	return err
+}
@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Message 一封待发送的邮件
+type Message struct {
+	To       []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+// Mailer 邮件发送接口，SMTP 用于生产环境，LogMailer 用于本地开发和测试
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+var (
+	defaultMailer Mailer
+	defaultOnce   sync.Once
+)
+
+// Default 返回根据环境变量创建的全局 Mailer，生产环境初始化失败时直接退出
+func Default() Mailer {
+	defaultOnce.Do(func() {
+		m, err := NewMailerFromEnv()
+		if err != nil {
+			// 日志输出会把验证码和重置链接写进日志，生产环境不能退回
+			if isProduction() {
+				log.Fatalf("邮件服务初始化失败: %v", err)
+			}
+			log.Printf("⚠️ 邮件服务初始化失败，改用日志输出: %v", err)
+			m = NewLogMailer("")
+		}
+		defaultMailer = m
+	})
+	return defaultMailer
+}
+
+// SetDefault 替换全局 Mailer（测试时可注入自定义实现）
+func SetDefault(m Mailer) {
+	defaultOnce.Do(func() {})
+	defaultMailer = m
+}
+
+// NewMailerFromEnv 根据 MAIL_DRIVER 创建 Mailer
+//   - smtp: 使用 SMTP_HOST/SMTP_PORT/SMTP_USERNAME/SMTP_PASSWORD/MAIL_FROM
+//   - log (默认): 写入 MAIL_LOG_FILE，未设置时输出到标准日志
+//
+// ENVIRONMENT=production 时必须使用 smtp
+func NewMailerFromEnv() (Mailer, error) {
+	driver := strings.ToLower(os.Getenv("MAIL_DRIVER"))
+	if isProduction() && driver != "smtp" {
+		return nil, fmt.Errorf("生产环境必须设置 MAIL_DRIVER=smtp，当前为 %q", driver)
+	}
+	switch driver {
+	case "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	case "", "log", "file":
+		return NewLogMailer(os.Getenv("MAIL_LOG_FILE")), nil
+	default:
+		return nil, fmt.Errorf("不支持的 MAIL_DRIVER: %s", driver)
+	}
+}
+
+func isProduction() bool {
+	return os.Getenv("ENVIRONMENT") == "production"
+}
+
+// SendTemplate 渲染指定模板并发送给收件人
+func SendTemplate(ctx context.Context, m Mailer, to string, name string, data any) error {
+	msg, err := Render(name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{to}
+	return m.Send(ctx, msg)
+}
@@ -0,0 +1,140 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig SMTP 服务器配置
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer 通过 SMTP 发送邮件
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPMailer 创建 SMTP Mailer
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, fmt.Errorf("SMTP 配置缺失，请检查 SMTP_HOST 和 MAIL_FROM")
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	return &SMTPMailer{cfg: cfg}, nil
+}
+
+// Send 发送邮件。465 端口使用隐式 TLS，其余端口在服务器支持时使用 STARTTLS
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("收件人不能为空")
+	}
+	body, err := m.buildMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if m.cfg.Port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: m.cfg.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("创建 SMTP 客户端失败: %v", err)
+	}
+	defer client.Close()
+
+	if m.cfg.Port != "465" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+				return fmt.Errorf("STARTTLS 失败: %v", err)
+			}
+		}
+	}
+	if m.cfg.Username != "" {
+		auth := smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %v", err)
+		}
+	}
+	if err := client.Mail(m.cfg.From); err != nil {
+		return fmt.Errorf("设置发件人失败: %v", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("设置收件人失败: %v", err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("写入邮件失败: %v", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return fmt.Errorf("写入邮件失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("写入邮件失败: %v", err)
+	}
+	return client.Quit()
+}
+
+// buildMessage 构造 multipart/alternative 格式的邮件内容
+func (m *SMTPMailer) buildMessage(msg *Message) ([]byte, error) {
+	boundaryBytes := make([]byte, 12)
+	if _, err := rand.Read(boundaryBytes); err != nil {
+		return nil, err
+	}
+	boundary := "ar-" + hex.EncodeToString(boundaryBytes)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(msg.TextBody)
+	buf.WriteString("\r\n")
+
+	if msg.HTMLBody != "" {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		buf.WriteString(msg.HTMLBody)
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
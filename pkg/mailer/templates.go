@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// 每个模板文件需定义 subject、text、html 三个子模板
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFS, "templates/*.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.tmpl"))
+)
+
+// Render 渲染指定名称的邮件模板（如 "verify_email"）
+func Render(name string, data any) (*Message, error) {
+	subject, err := executeText(name+".subject", data)
+	if err != nil {
+		return nil, err
+	}
+	text, err := executeText(name+".text", data)
+	if err != nil {
+		return nil, err
+	}
+
+	var html bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return nil, fmt.Errorf("渲染邮件模板 %s 失败: %v", name, err)
+	}
+
+	return &Message{
+		Subject:  strings.TrimSpace(subject),
+		TextBody: strings.TrimSpace(text),
+		HTMLBody: strings.TrimSpace(html.String()),
+	}, nil
+}
+
+func executeText(name string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := textTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("渲染邮件模板 %s 失败: %v", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,131 @@
+// Package textdiff 基于最长公共子序列（LCS）计算两段文本的逐行差异
+package textdiff
+
+import "strings"
+
+// 差异行的类型
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+)
+
+// maxCells LCS 表的最大单元数，去掉相同的首尾后仍超过时按整段替换处理，避免超长文本占用过多内存
+const maxCells = 4_000_000
+
+// Line 一行差异。OldLine、NewLine 为从 1 开始的行号，该行不存在于对应版本时为 0
+type Line struct {
+	Op      string `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// SplitLines 按行拆分文本，统一 \r\n 换行，末尾换行不产生空行
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Lines 计算从 a 到 b 的逐行差异
+func Lines(a, b string) []Line {
+	return Diff(SplitLines(a), SplitLines(b))
+}
+
+// Diff 计算从 a 到 b 的差异，同一位置的修改先输出删除行再输出新增行
+func Diff(a, b []string) []Line {
+	// 相同的首尾不参与 LCS 计算
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	out := make([]Line, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		out = append(out, Line{Op: OpEqual, Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	out = append(out, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		oi, ni := len(a)-suffix+i, len(b)-suffix+i
+		out = append(out, Line{Op: OpEqual, Text: a[oi], OldLine: oi + 1, NewLine: ni + 1})
+	}
+	return out
+}
+
+// diffMiddle 对去掉首尾后的部分做 LCS 回溯，oldOff、newOff 为行号偏移
+func diffMiddle(a, b []string, oldOff, newOff int) []Line {
+	n, m := len(a), len(b)
+	var out []Line
+	del := func(i int) { out = append(out, Line{Op: OpDelete, Text: a[i], OldLine: oldOff + i + 1}) }
+	ins := func(j int) { out = append(out, Line{Op: OpInsert, Text: b[j], NewLine: newOff + j + 1}) }
+
+	if n == 0 || m == 0 || (n+1)*(m+1) > maxCells {
+		for i := 0; i < n; i++ {
+			del(i)
+		}
+		for j := 0; j < m; j++ {
+			ins(j)
+		}
+		return out
+	}
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, Line{Op: OpEqual, Text: a[i], OldLine: oldOff + i + 1, NewLine: newOff + j + 1})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			del(i)
+			i++
+		default:
+			ins(j)
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		del(i)
+	}
+	for ; j < m; j++ {
+		ins(j)
+	}
+	return out
+}
+
+// Stats 统计新增和删除的行数
+func Stats(lines []Line) (inserted int, deleted int) {
+	for _, l := range lines {
+		switch l.Op {
+		case OpInsert:
+			inserted++
+		case OpDelete:
+			deleted++
+		}
+	}
+	return inserted, deleted
+}
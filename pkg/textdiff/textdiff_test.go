@@ -0,0 +1,141 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"空文本", "", nil},
+		{"单行", "a", []string{"a"}},
+		{"末尾换行不产生空行", "a\nb\n", []string{"a", "b"}},
+		{"CRLF", "a\r\nb\r\n", []string{"a", "b"}},
+		{"中间空行保留", "a\n\nb", []string{"a", "", "b"}},
+		{"只有换行", "\n", []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitLines(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitLines(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func eq(text string, oldLine, newLine int) Line {
+	return Line{Op: OpEqual, Text: text, OldLine: oldLine, NewLine: newLine}
+}
+
+func ins(text string, newLine int) Line {
+	return Line{Op: OpInsert, Text: text, NewLine: newLine}
+}
+
+func del(text string, oldLine int) Line {
+	return Line{Op: OpDelete, Text: text, OldLine: oldLine}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []Line
+	}{
+		{"都为空", nil, nil, []Line{}},
+		{"完全相同", []string{"a", "b"}, []string{"a", "b"}, []Line{eq("a", 1, 1), eq("b", 2, 2)}},
+		{"全部新增", nil, []string{"a", "b"}, []Line{ins("a", 1), ins("b", 2)}},
+		{"全部删除", []string{"a", "b"}, nil, []Line{del("a", 1), del("b", 2)}},
+		{
+			"中间插入",
+			[]string{"a", "c"}, []string{"a", "b", "c"},
+			[]Line{eq("a", 1, 1), ins("b", 2), eq("c", 2, 3)},
+		},
+		{
+			"中间删除",
+			[]string{"a", "b", "c"}, []string{"a", "c"},
+			[]Line{eq("a", 1, 1), del("b", 2), eq("c", 3, 2)},
+		},
+		{
+			"修改一行先删后增",
+			[]string{"a", "b", "c"}, []string{"a", "x", "c"},
+			[]Line{eq("a", 1, 1), del("b", 2), ins("x", 2), eq("c", 3, 3)},
+		},
+		{
+			"LCS 保留最长公共部分",
+			[]string{"a", "b", "c", "d"}, []string{"b", "x", "d", "e"},
+			[]Line{del("a", 1), eq("b", 2, 1), del("c", 3), ins("x", 2), eq("d", 4, 3), ins("e", 4)},
+		},
+		{
+			"重复行优先匹配相同的首尾",
+			[]string{"a", "a", "b"}, []string{"a", "b", "b"},
+			[]Line{eq("a", 1, 1), del("a", 2), ins("b", 2), eq("b", 3, 3)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff(%q, %q)\n got  %v\n want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffReconstructs 差异中的 equal+delete 行还原旧文本，equal+insert 行还原新文本
+func TestDiffReconstructs(t *testing.T) {
+	pairs := [][2]string{
+		{"", "a\nb"},
+		{"a\nb\nc\nd\ne", "a\nc\nd\nf\ne"},
+		{"x\ny\nz", "z\ny\nx"},
+		{"第一行\n第二行\n第三行", "第一行\n第 2 行\n第三行\n第四行"},
+	}
+	for _, p := range pairs {
+		var oldLines, newLines []string
+		for _, l := range Lines(p[0], p[1]) {
+			if l.Op != OpInsert {
+				oldLines = append(oldLines, l.Text)
+			}
+			if l.Op != OpDelete {
+				newLines = append(newLines, l.Text)
+			}
+		}
+		if got := strings.Join(oldLines, "\n"); got != p[0] {
+			t.Errorf("旧文本还原为 %q, want %q", got, p[0])
+		}
+		if got := strings.Join(newLines, "\n"); got != p[1] {
+			t.Errorf("新文本还原为 %q, want %q", got, p[1])
+		}
+	}
+}
+
+func TestDiffFallsBackWhenTooLarge(t *testing.T) {
+	// 去掉首尾后超过 maxCells 时按整段替换处理
+	n := 2100
+	a := make([]string, n)
+	b := make([]string, n)
+	for i := range a {
+		a[i] = "a" + strings.Repeat("x", i%7)
+		b[i] = "b" + strings.Repeat("x", i%7)
+	}
+	lines := Diff(a, b)
+	inserted, deleted := Stats(lines)
+	if inserted != n || deleted != n || len(lines) != 2*n {
+		t.Fatalf("inserted=%d deleted=%d lines=%d, want %d/%d/%d", inserted, deleted, len(lines), n, n, 2*n)
+	}
+	for i := 0; i < n; i++ {
+		if lines[i].Op != OpDelete || lines[n+i].Op != OpInsert {
+			t.Fatalf("line %d: 应先输出全部删除行再输出新增行", i)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	inserted, deleted := Stats(Lines("a\nb\nc", "a\nx\ny\nc"))
+	if inserted != 2 || deleted != 1 {
+		t.Errorf("Stats = (%d, %d), want (2, 1)", inserted, deleted)
+	}
+}
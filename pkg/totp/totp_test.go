@@ -0,0 +1,102 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret RFC 6238 附录 B 中 SHA1 使用的密钥 "12345678901234567890" 的 base32 编码
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeAtRFC6238(t *testing.T) {
+	// RFC 6238 附录 B 的 8 位结果取后 6 位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("CodeAt(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("CodeAt(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAtSecretFormat(t *testing.T) {
+	want, _ := CodeAt(rfcSecret, 1)
+	for _, secret := range []string{
+		strings.ToLower(rfcSecret),
+		"GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ",
+		rfcSecret + "======",
+	} {
+		got, err := CodeAt(secret, 1)
+		if err != nil || got != want {
+			t.Errorf("CodeAt(%q) = %s, %v, want %s", secret, got, err, want)
+		}
+	}
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("expected error for invalid secret")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(s int64) string {
+		c, err := CodeAt(rfcSecret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"当前时间步", code(step), 1, step, true},
+		{"前一个时间步", code(step - 1), 1, step - 1, true},
+		{"后一个时间步", code(step + 1), 1, step + 1, true},
+		{"超出偏差窗口", code(step - 2), 1, 0, false},
+		{"不允许偏差", code(step - 1), 0, 0, false},
+		{"前后空格", " " + code(step) + " ", 1, step, true},
+		{"位数不对", code(step)[:5], 1, 0, false},
+		{"错误验证码", "000000", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Validate = (%d, %v), want (%d, %v)", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateSecret()
+	if a == b {
+		t.Error("secrets should be random")
+	}
+	if key, err := decodeSecret(a); err != nil || len(key) != secretSize {
+		t.Errorf("decoded secret length = %d, err = %v", len(key), err)
+	}
+}